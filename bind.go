@@ -51,6 +51,20 @@ type GroupSet struct {
 
 type ArraySet []any
 
+// In returns values as the list of an IN clause, `WHERE id IN ?` bound to In(ids) becomes
+// `WHERE id IN (1, 2, 3)`. An empty list becomes (NULL), which no value is in unless the
+// transform_null_in setting is enabled, so the predicate is always false.
+func In[T any](values []T) GroupSet {
+	if len(values) == 0 {
+		return GroupSet{Value: []any{nil}}
+	}
+	set := GroupSet{Value: make([]any, len(values))}
+	for i, v := range values {
+		set.Value[i] = v
+	}
+	return set
+}
+
 func DateNamed(name string, value time.Time, scale TimeUnit) driver.NamedDateValue {
 	return driver.NamedDateValue{
 		Name:  name,
@@ -275,17 +289,29 @@ func formatTime(tz *time.Location, scale TimeUnit, value time.Time) (string, err
 	return fmt.Sprintf("toDateTime64('%s', %d, '%s')", value.Format(fmt.Sprintf("2006-01-02 15:04:05.%0*d", int(scale*3), 0)), int(scale*3), value.Location().String()), nil
 }
 
-var stringQuoteReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+var (
+	stringQuoteReplacer     = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	identifierQuoteReplacer = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+)
+
+// Quote returns s as a single quoted string literal, for queries that have to be built by hand
+// where a placeholder can't be used.
+func Quote(s string) string {
+	return "'" + stringQuoteReplacer.Replace(s) + "'"
+}
+
+// QuoteIdentifier returns name as a backtick quoted identifier, e.g. for a table or column name
+// in a dynamic ALTER statement.
+func QuoteIdentifier(name string) string {
+	return "`" + identifierQuoteReplacer.Replace(name) + "`"
+}
 
 func format(tz *time.Location, scale TimeUnit, v any) (string, error) {
-	quote := func(v string) string {
-		return "'" + stringQuoteReplacer.Replace(v) + "'"
-	}
 	switch v := v.(type) {
 	case nil:
 		return "NULL", nil
 	case string:
-		return quote(v), nil
+		return Quote(v), nil
 	case time.Time:
 		return formatTime(tz, scale, v)
 	case bool:
@@ -317,7 +343,7 @@ func format(tz *time.Location, scale TimeUnit, v any) (string, error) {
 			v.Type().Elem().Implements(reflect.TypeOf((*fmt.Stringer)(nil)).Elem()) {
 			return "NULL", nil
 		}
-		return quote(v.String()), nil
+		return Quote(v.String()), nil
 	case column.OrderedMap:
 		values := make([]string, 0)
 		for key := range v.Keys() {
@@ -354,7 +380,7 @@ func format(tz *time.Location, scale TimeUnit, v any) (string, error) {
 	}
 	switch v := reflect.ValueOf(v); v.Kind() {
 	case reflect.String:
-		return quote(v.String()), nil
+		return Quote(v.String()), nil
 	case reflect.Slice, reflect.Array:
 		values := make([]string, 0, v.Len())
 		for i := 0; i < v.Len(); i++ {
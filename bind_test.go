@@ -328,6 +328,20 @@ func TestFormatGroup(t *testing.T) {
 	}
 }
 
+func TestBindIn(t *testing.T) {
+	query, err := bind(time.UTC, "SELECT * FROM t WHERE id IN ? AND name IN ?", In([]int{1, 2, 3}), In([]string{"a", "it's"}))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE id IN (1, 2, 3) AND name IN ('a', 'it\\'s')", query)
+
+	query, err = bind(time.UTC, "SELECT * FROM t WHERE id IN @ids", Named("ids", In([]uint64{7})))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE id IN (7)", query)
+
+	query, err = bind(time.UTC, "SELECT * FROM t WHERE id IN $1", In([]int{}))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE id IN (NULL)", query, "an empty list matches nothing")
+}
+
 func TestFormatArray(t *testing.T) {
 	arraySet := ArraySet{"A", 1}
 	val, _ := format(time.UTC, Seconds, arraySet)
@@ -457,3 +471,37 @@ func BenchmarkBindNamed(b *testing.B) {
 		}
 	}
 }
+
+func TestQuote(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", `'plain'`},
+		{"it's", `'it\'s'`},
+		{`back\slash`, `'back\\slash'`},
+		{`\'; DROP TABLE t; --`, `'\\\'; DROP TABLE t; --'`},
+		{"multi\nline", "'multi\nline'"},
+		{"", `''`},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, Quote(tc.value))
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"events", "`events`"},
+		{"my table", "`my table`"},
+		{"odd`name", "`odd\\`name`"},
+		{`back\slash`, "`back\\\\slash`"},
+		{"t`; DROP TABLE t; --", "`t\\`; DROP TABLE t; --`"},
+		{"multi\nline", "`multi\nline`"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, QuoteIdentifier(tc.name))
+	}
+}
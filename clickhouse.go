@@ -21,6 +21,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 	"sync/atomic"
 	"time"
 
@@ -40,6 +42,9 @@ type (
 	Exception     = proto.Exception
 	ProfileInfo   = proto.ProfileInfo
 	ServerVersion = proto.ServerHandshake
+	TableName     = proto.TableName
+	TableStatus   = proto.TableStatus
+	Interval      = column.IntervalValue
 )
 
 var (
@@ -51,6 +56,11 @@ var (
 	ErrBindMixedParamsFormats    = errors.New("clickhouse [bind]: mixed named, numeric or positional parameters")
 	ErrAcquireConnNoAddress      = errors.New("clickhouse: no valid address supplied")
 	ErrServerUnexpectedData      = errors.New("code: 101, message: Unexpected packet Data received from client")
+	ErrTxIsolationNotSupported   = errors.New("clickhouse: transaction isolation levels are not supported, use the default level")
+	ErrTxReadOnlyNotSupported    = errors.New("clickhouse: read-only transactions are not supported")
+	ErrHTTPTablesStatus          = errors.New("clickhouse: tables status is only available over the native protocol")
+	ErrInsertSelectBatch         = errors.New("clickhouse: INSERT INTO ... SELECT takes no client data, run it with Exec instead of a batch")
+	ErrConnBusy                  = errors.New("clickhouse: connection is busy with another request, read or close its rows first")
 )
 
 type OpError struct {
@@ -77,27 +87,83 @@ func (e *OpError) Error() string {
 	return fmt.Sprintf("clickhouse [%s]: %s", e.Op, e.Err)
 }
 
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
 func Open(opt *Options) (driver.Conn, error) {
 	if opt == nil {
 		opt = &Options{}
 	}
 	o := opt.setDefaults()
+	if o.Protocol == HTTP {
+		return openHTTP(o), nil
+	}
 	conn := &clickhouse{
-		opt:  o,
-		idle: make(chan *connect, o.MaxIdleConns),
-		open: make(chan struct{}, o.MaxOpenConns),
-		exit: make(chan struct{}),
+		opt:      o,
+		idle:     make(chan *connect, o.MaxIdleConns),
+		open:     make(chan struct{}, o.MaxOpenConns),
+		exit:     make(chan struct{}),
+		settings: &defaultSettings{},
 	}
 	go conn.startAutoCloseIdleConnections()
 	return conn, nil
 }
 
+// Validate parses the DSN and dials every address of it, each connection is closed again right after
+// the handshake. It returns nil once one address answers, as Open would use it, and otherwise an error
+// naming the stage each address failed at: parsing the DSN, the dial, the authentication, e.g. a wrong
+// password, or the handshake of the protocol, e.g. when the address isn't a ClickHouse server.
+func Validate(dsn string) error {
+	opt, err := ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf("clickhouse [validate]: parse dsn: %w", err)
+	}
+	o := opt.setDefaults()
+	ctx, cancel := context.WithTimeout(context.Background(), o.DialTimeout)
+	defer cancel()
+	var errs []error
+	for num, addr := range o.Addr {
+		var closer interface{ close() error }
+		switch o.Protocol {
+		case HTTP:
+			closer, err = dialHttp(ctx, addr, num+1, o)
+		default:
+			closer, err = dial(ctx, addr, num+1, o)
+		}
+		if err == nil {
+			return closer.close()
+		}
+		errs = append(errs, fmt.Errorf("clickhouse [validate]: %s %s: %w", validateStage(err), addr, err))
+	}
+	return errors.Join(errs...)
+}
+
+// validateStage names the stage of a connection that failed with err, see Validate.
+func validateStage(err error) string {
+	var (
+		dialErr   *dialError
+		exception *Exception
+		statusErr *httpStatusError
+	)
+	switch {
+	case errors.As(err, &dialErr):
+		return "dial"
+	case errors.As(err, &exception) && exception.Code == CodeAuthenticationFailed:
+		return "authenticate with"
+	case errors.As(err, &statusErr) && (statusErr.statusCode == http.StatusUnauthorized || statusErr.statusCode == http.StatusForbidden):
+		return "authenticate with"
+	}
+	return "handshake with"
+}
+
 type clickhouse struct {
-	opt    *Options
-	idle   chan *connect
-	open   chan struct{}
-	exit   chan struct{}
-	connID int64
+	opt      *Options
+	idle     chan *connect
+	open     chan struct{}
+	exit     chan struct{}
+	connID   int64
+	settings *defaultSettings
 }
 
 func (clickhouse) Contributors() []string {
@@ -122,23 +188,58 @@ func (ch *clickhouse) ServerVersion() (*driver.ServerVersion, error) {
 }
 
 func (ch *clickhouse) Query(ctx context.Context, query string, args ...any) (rows driver.Rows, err error) {
-	conn, err := ch.acquire(ctx)
+	r, err := ch.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	conn.debugf("[acquired] connection [%d]", conn.id)
-	return conn.query(ctx, ch.release, query, args...)
+	return r, nil
+}
+
+func (ch *clickhouse) QueryBlocks(ctx context.Context, query string, args ...any) (<-chan driver.Block, error) {
+	r, err := ch.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.blocks(ctx), nil
 }
 
 func (ch *clickhouse) QueryRow(ctx context.Context, query string, args ...any) (rows driver.Row) {
-	conn, err := ch.acquire(ctx)
+	r, err := ch.query(ctx, query, args...)
 	if err != nil {
 		return &row{
 			err: err,
 		}
 	}
-	conn.debugf("[acquired] connection [%d]", conn.id)
-	return conn.queryRow(ctx, ch.release, query, args...)
+	return &row{
+		rows: r,
+	}
+}
+
+// readOnlyQueryRe matches the queries that are safe to run a second time, see query.
+var readOnlyQueryRe = regexp.MustCompile(`(?is)^\s*(SELECT|WITH)\s`)
+
+// query runs a query on a pooled connection. A SELECT that failed because the connection was
+// broken before the server answered it with any packet is re-issued on a fresh one up to
+// Options.RetryReads times. Nothing has been handed to the caller at that point, rows that fail
+// later are never retried.
+func (ch *clickhouse) query(ctx context.Context, query string, args ...any) (*rows, error) {
+	retry := ch.opt.RetryReads
+	if !readOnlyQueryRe.MatchString(query) {
+		retry = 0
+	}
+	for attempt := 0; ; attempt++ {
+		conn, err := ch.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conn.debugf("[acquired] connection [%d] to %s", conn.id, conn.addr)
+		r, err := conn.query(ctx, ch.release, query, args...)
+		// a failed query closes its connection, it's no longer shared when looked at here
+		if err == nil || attempt >= retry || !isConnBrokenError(err) || conn.responded || ctx.Err() != nil {
+			return r, err
+		}
+		conn.debugf("[query] retrying on a fresh connection after: %v", err)
+	}
 }
 
 func (ch *clickhouse) Exec(ctx context.Context, query string, args ...any) error {
@@ -189,6 +290,19 @@ func (ch *clickhouse) AsyncInsert(ctx context.Context, query string, wait bool,
 	return nil
 }
 
+// TablesStatus reports whether the tables are replicated and how far the replica lags behind,
+// which helps routing reads to replicas that have caught up. At most 10000 tables are asked
+// about at once.
+func (ch *clickhouse) TablesStatus(ctx context.Context, tables []TableName) (map[TableName]TableStatus, error) {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := conn.tablesStatus(ctx, tables)
+	ch.release(conn, err)
+	return status, err
+}
+
 func (ch *clickhouse) Ping(ctx context.Context) (err error) {
 	conn, err := ch.acquire(ctx)
 	if err != nil {
@@ -211,6 +325,18 @@ func (ch *clickhouse) Stats() driver.Stats {
 	}
 }
 
+func (ch *clickhouse) SetSetting(name string, value any) {
+	ch.settings.set(name, value)
+}
+
+func (ch *clickhouse) ClearSettings() {
+	ch.settings.clear()
+}
+
+var _ driver.SettingsConn = (*clickhouse)(nil)
+var _ driver.BlocksConn = (*clickhouse)(nil)
+var _ driver.TablesStatusConn = (*clickhouse)(nil)
+
 func (ch *clickhouse) dial(ctx context.Context) (conn *connect, err error) {
 	connID := int(atomic.AddInt64(&ch.connID, 1))
 
@@ -229,20 +355,15 @@ func (ch *clickhouse) dial(ctx context.Context) (conn *connect, err error) {
 	if err != nil {
 		return nil, err
 	}
+	result.conn.defaults = ch.settings
 	return result.conn, nil
 }
 
 func DefaultDialStrategy(ctx context.Context, connID int, opt *Options, dial Dial) (r DialResult, err error) {
-	for i := range opt.Addr {
-		var num int
-		switch opt.ConnOpenStrategy {
-		case ConnOpenInOrder:
-			num = i
-		case ConnOpenRoundRobin:
-			num = (int(connID) + i) % len(opt.Addr)
-		}
-
-		if r, err = dial(ctx, opt.Addr[num], opt); err == nil {
+	for _, num := range opt.hostOrder(connID) {
+		r, err = dial(ctx, opt.Addr[num], opt)
+		opt.dialed(ctx, opt.Addr[num], err)
+		if err == nil {
 			return r, nil
 		}
 	}
@@ -277,7 +398,8 @@ func (ch *clickhouse) acquire(ctx context.Context) (conn *connect, err error) {
 		}
 		return nil, ErrAcquireConnTimeout
 	case conn := <-ch.idle:
-		if conn.isBad() {
+		// a connection idle for longer than the server keeps it open is dialed again up front
+		if conn.isBad() || time.Since(conn.releasedAt) >= ch.opt.ConnMaxIdleTime() {
 			conn.close()
 			if conn, err = ch.dial(ctx); err != nil {
 				select {
@@ -340,7 +462,7 @@ func (ch *clickhouse) release(conn *connect, err error) {
 	if conn.released {
 		return
 	}
-	conn.released = true
+	conn.released, conn.releasedAt = true, time.Now()
 	select {
 	case <-ch.open:
 	default:
@@ -0,0 +1,256 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// httpClickhouse is the driver.Conn returned by Open for the HTTP protocol. It pools HTTP
+// connections the same way clickhouse pools native ones, so callers don't have to change
+// code when switching between the two.
+type httpClickhouse struct {
+	opt      *Options
+	idle     chan *httpConnect
+	open     chan struct{}
+	connID   int64
+	settings *defaultSettings
+}
+
+func openHTTP(o *Options) *httpClickhouse {
+	return &httpClickhouse{
+		opt:      o,
+		idle:     make(chan *httpConnect, o.MaxIdleConns),
+		open:     make(chan struct{}, o.MaxOpenConns),
+		settings: &defaultSettings{},
+	}
+}
+
+func (ch *httpClickhouse) Contributors() []string {
+	return clickhouse{}.Contributors()
+}
+
+func (ch *httpClickhouse) ServerVersion() (*driver.ServerVersion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ch.opt.DialTimeout)
+	defer cancel()
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := conn.readVersion(ctx)
+	ch.release(conn, err)
+	if err != nil {
+		return nil, err
+	}
+	return &driver.ServerVersion{
+		Name:     "ClickHouse",
+		Version:  version,
+		Timezone: conn.location,
+		Addr:     conn.addr,
+	}, nil
+}
+
+func (ch *httpClickhouse) Select(ctx context.Context, dest any, query string, args ...any) error {
+	return selectInto(dest, func() (driver.Rows, error) {
+		return ch.Query(ctx, query, args...)
+	})
+}
+
+func (ch *httpClickhouse) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the rows read from their own response, so the connection can be reused right away
+	r, err := conn.query(ctx, nil, query, args...)
+	ch.release(conn, err)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (ch *httpClickhouse) QueryBlocks(ctx context.Context, query string, args ...any) (<-chan driver.Block, error) {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := conn.query(ctx, nil, query, args...)
+	ch.release(conn, err)
+	if err != nil {
+		return nil, err
+	}
+	return r.blocks(ctx), nil
+}
+
+func (ch *httpClickhouse) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return &row{err: err}
+	}
+	r, err := conn.query(ctx, nil, query, args...)
+	ch.release(conn, err)
+	if err != nil {
+		return &row{err: err}
+	}
+	return &row{rows: r}
+}
+
+func (ch *httpClickhouse) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := conn.prepareBatch(ctx, query, getPrepareBatchOptions(opts...), nil, nil)
+	if err != nil {
+		ch.release(conn, err)
+		return nil, err
+	}
+	// the batch encodes into the connection buffer, which is held until it's sent or aborted
+	batch.(*httpBatch).release = func(err error) {
+		ch.release(conn, err)
+	}
+	return batch, nil
+}
+
+func (ch *httpClickhouse) Exec(ctx context.Context, query string, args ...any) error {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = conn.exec(ctx, query, args...)
+	ch.release(conn, err)
+	return err
+}
+
+func (ch *httpClickhouse) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = conn.asyncInsert(ctx, query, wait, args...)
+	ch.release(conn, err)
+	return err
+}
+
+func (ch *httpClickhouse) Ping(ctx context.Context) error {
+	conn, err := ch.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = conn.ping(ctx)
+	ch.release(conn, err)
+	return err
+}
+
+func (ch *httpClickhouse) TablesStatus(context.Context, []TableName) (map[TableName]TableStatus, error) {
+	return nil, ErrHTTPTablesStatus
+}
+
+func (ch *httpClickhouse) Stats() driver.Stats {
+	return driver.Stats{
+		Open:         len(ch.open),
+		Idle:         len(ch.idle),
+		MaxOpenConns: cap(ch.open),
+		MaxIdleConns: cap(ch.idle),
+	}
+}
+
+func (ch *httpClickhouse) SetSetting(name string, value any) {
+	ch.settings.set(name, value)
+}
+
+func (ch *httpClickhouse) ClearSettings() {
+	ch.settings.clear()
+}
+
+func (ch *httpClickhouse) Close() error {
+	for {
+		select {
+		case c := <-ch.idle:
+			c.close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (ch *httpClickhouse) dial(ctx context.Context) (conn *httpConnect, err error) {
+	if len(ch.opt.Addr) == 0 {
+		return nil, ErrAcquireConnNoAddress
+	}
+	connID := int(atomic.AddInt64(&ch.connID, 1))
+	for _, num := range ch.opt.hostOrder(connID) {
+		conn, err = dialHttp(ctx, ch.opt.Addr[num], connID, ch.opt)
+		ch.opt.dialed(ctx, ch.opt.Addr[num], err)
+		if err == nil {
+			conn.defaults = ch.settings
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+func (ch *httpClickhouse) acquire(ctx context.Context) (*httpConnect, error) {
+	timer := time.NewTimer(ch.opt.DialTimeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil, ErrAcquireConnTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case ch.open <- struct{}{}:
+	}
+	select {
+	case conn := <-ch.idle:
+		if !conn.isBad() {
+			return conn, nil
+		}
+		conn.close()
+	default:
+	}
+	conn, err := ch.dial(ctx)
+	if err != nil {
+		<-ch.open
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (ch *httpClickhouse) release(conn *httpConnect, err error) {
+	<-ch.open
+	// errors reported by the server in a response leave the connection usable
+	if isConnBrokenError(err) || conn.isBad() {
+		conn.close()
+		return
+	}
+	select {
+	case ch.idle <- conn:
+	default:
+		conn.close()
+	}
+}
+
+var _ driver.Conn = (*httpClickhouse)(nil)
+var _ driver.SettingsConn = (*httpClickhouse)(nil)
+var _ driver.BlocksConn = (*httpClickhouse)(nil)
+var _ driver.TablesStatusConn = (*httpClickhouse)(nil)
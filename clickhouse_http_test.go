@@ -0,0 +1,286 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	chproto "github.com/ClickHouse/ch-go/proto"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHTTPServer answers queries the way the HTTP interface does with default_format=Native
+// and keeps the values inserted into a single UInt64 column table.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	inserted []uint64
+	inserts  []string
+	timezone string // UTC when empty
+	settings []string
+}
+
+func (s *fakeHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query := r.URL.Query().Get("query"); query != "" {
+		if !strings.HasPrefix(query, "INSERT INTO t") || !strings.HasSuffix(query, " FORMAT Native") {
+			http.Error(w, "unexpected insert "+query, http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.inserts = append(s.inserts, query)
+		s.mu.Unlock()
+		reader := chproto.NewReader(strings.NewReader(string(body)))
+		for {
+			var block proto.Block
+			if err := block.Decode(reader, 0); err != nil {
+				break
+			}
+			if block.Rows() == 0 {
+				continue
+			}
+			s.mu.Lock()
+			for i := 0; i < block.Rows(); i++ {
+				s.inserted = append(s.inserted, block.Columns[0].Row(i, false).(uint64))
+			}
+			s.mu.Unlock()
+		}
+		return
+	}
+	s.mu.Lock()
+	s.settings = append(s.settings, r.URL.Query().Get("max_threads"))
+	s.mu.Unlock()
+
+	block := &proto.Block{}
+	add := func(name string, ct column.Type) {
+		if err := block.AddColumn(name, ct); err != nil {
+			panic(err)
+		}
+	}
+	switch query := string(body); query {
+	case "SELECT timezone()":
+		add("timezone()", "String")
+		timezone := "UTC"
+		if s.timezone != "" {
+			timezone = s.timezone
+		}
+		_ = block.Append(timezone)
+	case "SELECT version()":
+		add("version()", "String")
+		_ = block.Append("24.3.1.1")
+	case "SELECT 1":
+		add("1", "UInt8")
+		_ = block.Append(uint8(1))
+	case "DESCRIBE TABLE t":
+		for _, name := range []string{"name", "type", "default_type", "default_expression", "comment", "codec_expression", "ttl_expression"} {
+			add(name, "String")
+		}
+		_ = block.Append("n", "UInt64", "", "", "", "", "")
+	case "SELECT n FROM t":
+		add("n", "UInt64")
+		s.mu.Lock()
+		for _, n := range s.inserted {
+			_ = block.Append(n)
+		}
+		s.mu.Unlock()
+	case "SELECT n FROM truncated":
+		// two blocks and the start of a third one, as when the server fails mid-stream
+		add("n", "UInt64")
+		_ = block.Append(uint64(1))
+		var buf chproto.Buffer
+		for i := 0; i < 3; i++ {
+			if err := block.Encode(&buf, 0); err != nil {
+				panic(err)
+			}
+		}
+		_, _ = w.Write(buf.Buf[:len(buf.Buf)-4])
+		return
+	default:
+		http.Error(w, "unexpected query "+query, http.StatusBadRequest)
+		return
+	}
+	var buf chproto.Buffer
+	if err := block.Encode(&buf, 0); err != nil {
+		panic(err)
+	}
+	_, _ = w.Write(buf.Buf)
+}
+
+func TestOpenHTTP(t *testing.T) {
+	server := &fakeHTTPServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	conn, err := Open(&Options{
+		Addr:     []string{strings.TrimPrefix(ts.URL, "http://")},
+		Protocol: HTTP,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx := context.Background()
+
+	require.NoError(t, conn.Ping(ctx))
+	version, err := conn.ServerVersion()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(24), version.Version.Major)
+	assert.Equal(t, "UTC", version.Timezone.String())
+	assert.Equal(t, strings.TrimPrefix(ts.URL, "http://"), version.Addr)
+
+	batch, err := conn.PrepareBatch(ctx, "INSERT INTO t")
+	require.NoError(t, err)
+	assert.Equal(t, 1, conn.Stats().Open, "the batch holds its connection until it's sent")
+	for _, n := range []uint64{1, 2, 3} {
+		require.NoError(t, batch.Append(n))
+	}
+	require.NoError(t, batch.Send())
+	assert.Equal(t, 0, conn.Stats().Open)
+	assert.Equal(t, []uint64{1, 2, 3}, server.inserted)
+
+	conn.(driver.SettingsConn).SetSetting("max_threads", 2)
+	var result []struct {
+		N uint64 `ch:"n"`
+	}
+	require.NoError(t, conn.Select(ctx, &result, "SELECT n FROM t"))
+	require.Len(t, result, 3)
+	assert.Equal(t, uint64(3), result[2].N)
+
+	var first uint64
+	require.NoError(t, conn.QueryRow(ctx, "SELECT n FROM t").Scan(&first))
+	assert.Equal(t, uint64(1), first)
+	assert.Equal(t, "2", server.settings[len(server.settings)-1])
+	require.NoError(t, conn.QueryRow(Context(ctx, WithSettings(Settings{"max_threads": true})), "SELECT n FROM t").Scan(&first))
+	assert.Equal(t, "1", server.settings[len(server.settings)-1], "booleans are sent as 1 and 0")
+
+	err = conn.Exec(ctx, "DROP TABLE t")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected query DROP TABLE t")
+	assert.Equal(t, 1, conn.Stats().Idle, "connections are reused across calls")
+}
+
+func TestHTTPBatchSettingsClause(t *testing.T) {
+	server := &fakeHTTPServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	conn, err := Open(&Options{
+		Addr:     []string{strings.TrimPrefix(ts.URL, "http://")},
+		Protocol: HTTP,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, query := range []string{
+		"INSERT INTO t SETTINGS async_insert=1 VALUES",
+		"INSERT INTO t (n) SETTINGS async_insert=1",
+	} {
+		batch, err := conn.PrepareBatch(context.Background(), query)
+		require.NoError(t, err)
+		require.NoError(t, batch.Append(uint64(1)))
+		require.NoError(t, batch.Send())
+	}
+	assert.Equal(t, []string{
+		"INSERT INTO t SETTINGS async_insert=1 FORMAT Native",
+		"INSERT INTO t SETTINGS async_insert=1 FORMAT Native",
+	}, server.inserts)
+}
+
+func TestHTTPUnknownTimezone(t *testing.T) {
+	server := &fakeHTTPServer{timezone: "Mars/Olympus_Mons"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	conn, err := Open(&Options{
+		Addr:     []string{strings.TrimPrefix(ts.URL, "http://")},
+		Protocol: HTTP,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	version, err := conn.ServerVersion()
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, version.Location())
+}
+
+func TestHTTPQueryMidStreamError(t *testing.T) {
+	ts := httptest.NewServer(&fakeHTTPServer{})
+	defer ts.Close()
+
+	conn, err := Open(&Options{
+		Addr:     []string{strings.TrimPrefix(ts.URL, "http://")},
+		Protocol: HTTP,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT n FROM truncated")
+	require.NoError(t, err)
+	done := make(chan int)
+	go func() {
+		var n int
+		for rows.Next() {
+			n++
+		}
+		done <- n
+	}()
+	select {
+	case n := <-done:
+		assert.Equal(t, 2, n, "the rows read before the error are scanned")
+		assert.ErrorIs(t, rows.Err(), io.ErrUnexpectedEOF)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next blocked on the error of the stream")
+	}
+}
+
+func TestHTTPMaxBlockRows(t *testing.T) {
+	ts := httptest.NewServer(&fakeHTTPServer{inserted: []uint64{1, 2, 3}})
+	defer ts.Close()
+
+	for _, c := range []struct {
+		maxBlockRows int
+		err          bool
+	}{{2, true}, {3, false}} {
+		conn, err := Open(&Options{
+			Addr:         []string{strings.TrimPrefix(ts.URL, "http://")},
+			Protocol:     HTTP,
+			MaxBlockRows: c.maxBlockRows,
+		})
+		require.NoError(t, err)
+		rows, err := conn.Query(context.Background(), "SELECT n FROM t")
+		if c.err {
+			assert.ErrorContains(t, err, "3 rows in block exceed the limit of 2", "a block of 3 rows with MaxBlockRows %d", c.maxBlockRows)
+		} else {
+			require.NoError(t, err)
+			require.NoError(t, rows.Close())
+		}
+		conn.Close()
+	}
+}
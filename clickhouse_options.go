@@ -26,8 +26,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/ClickHouse/ch-go/compress"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 	"github.com/pkg/errors"
 )
 
@@ -87,6 +89,9 @@ type ConnOpenStrategy uint8
 const (
 	ConnOpenInOrder ConnOpenStrategy = iota
 	ConnOpenRoundRobin
+	// ConnOpenHealthy is round robin that tries the hosts that recently failed to connect last,
+	// backing off exponentially from one second to a minute.
+	ConnOpenHealthy
 )
 
 type Protocol int
@@ -127,13 +132,17 @@ type Options struct {
 	TLS                  *tls.Config
 	Addr                 []string
 	Auth                 Auth
-	DialContext          func(ctx context.Context, addr string) (net.Conn, error)
+	DialContext          func(ctx context.Context, addr string) (net.Conn, error) // replaces the default dialer for every Addr entry as is, bounded by DialTimeout, TLS and Proxy are left to it
 	DialStrategy         func(ctx context.Context, connID int, options *Options, dial Dial) (DialResult, error)
 	Debug                bool
 	Debugf               func(format string, v ...any) // only works when Debug is true
+	Trace                bool                          // hex dump raw bytes read and written, only works when Debug is true
+	Metrics              MetricsHook                   // receives query events, no-op when nil
 	Settings             Settings
+	QuotaKey             string // default quota key, sent with the handshake addendum (revision 54458) and every query (revision 54060). WithQuotaKey overrides it per query
 	Compression          *Compression
 	DialTimeout          time.Duration // default 30 second
+	TCPKeepAlive         time.Duration // default 15 second - interval of TCP keep-alive probes, negative disables them
 	MaxOpenConns         int           // default MaxIdleConns + 5
 	MaxIdleConns         int           // default 5
 	ConnMaxLifetime      time.Duration // default 1 hour
@@ -143,19 +152,90 @@ type Options struct {
 	HttpUrlPath          string            // set additional URL path for HTTP requests
 	BlockBufferSize      uint8             // default 2 - can be overwritten on query
 	MaxCompressionBuffer int               // default 10485760 - measured in bytes  i.e. 10MiB
+	MaxStringSize        int               // default 268435456 - largest string accepted from the server in bytes i.e. 256MiB
+	MaxBlockRows         int               // default 1000000000 - largest number of rows accepted in a block from the server
+	ReadBufferSize       int               // default 131072 - size in bytes of the buffer socket reads are made into, i.e. 128KiB, at most that is read at a time
+	TimeLayout           string            // default time.RFC3339 - layout of date and time values scanned into a string by Rows.Scan, database/sql always uses time.RFC3339Nano
+	Location             *time.Location    // default nil - DateTime values are decoded in the server time zone unless set, e.g. to time.UTC. WithUserLocation overrides it per query
 
-	scheme      string
+	scheme string
+	health *hostHealth // only set for ConnOpenHealthy
+	// ReadTimeout is the deadline of reading a response from the client socket. It's independent of the
+	// server side send_timeout, receive_timeout and max_execution_time settings, see WithServerTimeouts.
 	ReadTimeout time.Duration
+	// WriteTimeout bounds every single write to the connection, it is renewed for each flushed
+	// chunk so large inserts are not limited as a whole. Zero means no limit. Native protocol only.
+	WriteTimeout time.Duration
+	// RetryReads is the number of times Query, QueryRow and Select re-issue a SELECT on a fresh
+	// connection when the connection breaks before the server answered it. Other statements are
+	// never retried. Zero disables retries.
+	RetryReads int
+	// ServerIdleTimeout is how long the server keeps an idle connection open, its idle_connection_timeout
+	// setting, or keep_alive_timeout over HTTP. Zero means the server default, see ConnMaxIdleTime.
+	ServerIdleTimeout time.Duration
+	// ClientRevision caps the protocol revision advertised to the server, so the features introduced
+	// since are negotiated down as with an older client. It's meant for reproducing the behavior of
+	// old servers, zero advertises ClientTCPProtocolVersion. Native protocol only.
+	ClientRevision uint64
+	// Proxy is a SOCKS5 proxy, socks5://[user:password@]host:port, connections are made through,
+	// the TLS handshake is then made with the server over the proxied connection. Over HTTP it may
+	// be an HTTP proxy as well and replaces the one of the environment. Ignored with DialContext.
+	Proxy *url.URL
+	// IgnoreUnknownSettings has the server skip the settings it doesn't know, with a warning in its
+	// log, rather than fail the query, and leaves out the parameters of the HTTP interface, e.g.
+	// wait_end_of_query, so settings shared with HTTP clients can be used as they are. Native
+	// protocol only, servers older than revision 54429 fail regardless.
+	IgnoreUnknownSettings bool
+}
+
+// defaults of the server settings closing idle connections, idle_connection_timeout of the native
+// protocol and keep_alive_timeout of HTTP, which is the lowest value any server version defaults to
+const (
+	defaultServerIdleTimeout     = time.Hour
+	defaultServerHTTPIdleTimeout = 3 * time.Second
+)
+
+// ConnMaxIdleTime returns how long a connection may be idle in a pool before it's dialed again rather
+// than reused: a tenth less than ServerIdleTimeout, or the server default, so it's dropped before the
+// server closes it. OpenDB applies it to native connections, pass it to sql.DB.SetConnMaxIdleTime
+// when using sql.Open. Over HTTP it's the idle timeout of the transport's connections.
+func (o *Options) ConnMaxIdleTime() time.Duration {
+	timeout := o.ServerIdleTimeout
+	if timeout <= 0 {
+		timeout = defaultServerIdleTimeout
+		if o.Protocol == HTTP {
+			timeout = defaultServerHTTPIdleTimeout
+		}
+	}
+	return timeout - timeout/10
 }
 
 func (o *Options) fromDSN(in string) error {
+	if i := strings.Index(in, "://"); i == -1 || strings.ContainsAny(in[:i], "/?") {
+		// a DSN copied without its scheme, e.g. localhost:9000?database=foo, is a native one. A
+		// scheme only comes first, the "://" of a parameter such as proxy=socks5://... doesn't count
+		in = "tcp://" + in
+	}
 	dsn, err := url.Parse(in)
 	if err != nil {
 		return err
 	}
 
-	if dsn.Host == "" {
+	switch {
+	case dsn.Scheme == "unix" && dsn.Path != "":
+		// unix:///var/run/clickhouse/clickhouse.sock - the path names the socket, not the database
+		o.Addr = append(o.Addr, "unix://"+dsn.Path)
+	case dsn.Host == "":
 		return errors.New("parse dsn address failed")
+	default:
+		for _, host := range strings.Split(dsn.Host, ",") {
+			if _, _, err := net.SplitHostPort(host); err != nil && (dsn.Scheme == "tcp" || dsn.Scheme == "clickhouse") {
+				host = net.JoinHostPort(strings.Trim(host, "[]"), "9000")
+			}
+			o.Addr = append(o.Addr, host)
+		}
+		// tcp://host:9000/db names the database in the path, a database query parameter takes precedence
+		o.Auth.Database = strings.Trim(dsn.Path, "/")
 	}
 
 	if o.Settings == nil {
@@ -165,18 +245,18 @@ func (o *Options) fromDSN(in string) error {
 		o.Auth.Username = dsn.User.Username()
 		o.Auth.Password, _ = dsn.User.Password()
 	}
-	o.Addr = append(o.Addr, strings.Split(dsn.Host, ",")...)
 	var (
 		secure     bool
 		params     = dsn.Query()
 		skipVerify bool
 	)
-	o.Auth.Database = strings.TrimPrefix(dsn.Path, "/")
 
 	for v := range params {
 		switch v {
 		case "debug":
 			o.Debug, _ = strconv.ParseBool(params.Get(v))
+		case "trace":
+			o.Trace, _ = strconv.ParseBool(params.Get(v))
 		case "compress":
 			if on, _ := strconv.ParseBool(params.Get(v)); on {
 				if o.Compression == nil {
@@ -218,6 +298,32 @@ func (o *Options) fromDSN(in string) error {
 				return errors.Wrap(err, "max_compression_buffer invalid value")
 			}
 			o.MaxCompressionBuffer = max
+		case "max_string_size":
+			max, err := strconv.Atoi(params.Get(v))
+			if err != nil {
+				return errors.Wrap(err, "max_string_size invalid value")
+			}
+			o.MaxStringSize = max
+		case "max_block_rows":
+			max, err := strconv.Atoi(params.Get(v))
+			if err != nil {
+				return errors.Wrap(err, "max_block_rows invalid value")
+			}
+			o.MaxBlockRows = max
+		case "read_buffer_size":
+			size, err := strconv.Atoi(params.Get(v))
+			if err != nil {
+				return errors.Wrap(err, "read_buffer_size invalid value")
+			}
+			o.ReadBufferSize = size
+		case "time_layout":
+			o.TimeLayout = params.Get(v)
+		case "use_server_time_zone":
+			if use, err := strconv.ParseBool(params.Get(v)); err != nil {
+				return errors.Wrap(err, "use_server_time_zone invalid value")
+			} else if !use {
+				o.Location = time.UTC
+			}
 		case "dial_timeout":
 			duration, err := time.ParseDuration(params.Get(v))
 			if err != nil {
@@ -239,6 +345,48 @@ func (o *Options) fromDSN(in string) error {
 				return fmt.Errorf("clickhouse [dsn parse]:read timeout: %s", err)
 			}
 			o.ReadTimeout = duration
+		case "retry_reads":
+			retries, err := strconv.Atoi(params.Get(v))
+			if err != nil || retries < 0 {
+				return fmt.Errorf("clickhouse [dsn parse]: retry_reads must be a non-negative integer")
+			}
+			o.RetryReads = retries
+		case "tcp_keepalive":
+			duration, err := time.ParseDuration(params.Get(v))
+			if err != nil {
+				return fmt.Errorf("clickhouse [dsn parse]:tcp keepalive: %s", err)
+			}
+			o.TCPKeepAlive = duration
+		case "client_revision":
+			revision, err := strconv.ParseUint(params.Get(v), 10, 64)
+			if err != nil {
+				return fmt.Errorf("clickhouse [dsn parse]: client_revision must be a protocol revision")
+			}
+			o.ClientRevision = revision
+		case "server_idle_timeout":
+			duration, err := time.ParseDuration(params.Get(v))
+			if err != nil {
+				return fmt.Errorf("clickhouse [dsn parse]:server idle timeout: %s", err)
+			}
+			o.ServerIdleTimeout = duration
+		case "ignore_unknown_settings":
+			ignore, err := strconv.ParseBool(params.Get(v))
+			if err != nil {
+				return fmt.Errorf("clickhouse [dsn parse]:ignore_unknown_settings: %s", err)
+			}
+			o.IgnoreUnknownSettings = ignore
+		case "proxy":
+			proxy, err := url.Parse(params.Get(v))
+			if err != nil || proxy.Host == "" {
+				return fmt.Errorf("clickhouse [dsn parse]:proxy: %q is not a proxy URL", params.Get(v))
+			}
+			o.Proxy = proxy
+		case "write_timeout":
+			duration, err := time.ParseDuration(params.Get(v))
+			if err != nil {
+				return fmt.Errorf("clickhouse [dsn parse]:write timeout: %s", err)
+			}
+			o.WriteTimeout = duration
 		case "secure":
 			secureParam := params.Get(v)
 			if secureParam == "" {
@@ -265,6 +413,8 @@ func (o *Options) fromDSN(in string) error {
 				o.ConnOpenStrategy = ConnOpenInOrder
 			case "round_robin":
 				o.ConnOpenStrategy = ConnOpenRoundRobin
+			case "healthy":
+				o.ConnOpenStrategy = ConnOpenHealthy
 			}
 		case "max_open_conns":
 			maxOpenConns, err := strconv.Atoi(params.Get(v))
@@ -284,10 +434,16 @@ func (o *Options) fromDSN(in string) error {
 				return errors.Wrap(err, "conn_max_lifetime invalid value")
 			}
 			o.ConnMaxLifetime = connMaxLifetime
+		case "database":
+			o.Auth.Database = params.Get(v)
+		case "quota_key":
+			o.QuotaKey = params.Get(v)
 		case "username":
 			o.Auth.Username = params.Get(v)
 		case "password":
 			o.Auth.Password = params.Get(v)
+		case "client_name":
+			o.ClientInfo.Name = params.Get(v)
 		case "client_info_product":
 			chunks := strings.Split(params.Get(v), ",")
 
@@ -314,6 +470,9 @@ func (o *Options) fromDSN(in string) error {
 			}
 		}
 	}
+	if !isValidDatabase(o.Auth.Database) {
+		return fmt.Errorf("clickhouse [dsn parse]: invalid database name %q", o.Auth.Database)
+	}
 	if secure {
 		o.TLS = &tls.Config{
 			InsecureSkipVerify: skipVerify,
@@ -337,8 +496,131 @@ func (o *Options) fromDSN(in string) error {
 	return nil
 }
 
+// FormatDSN returns the DSN of the options, ParseDSN of it gives the same options back. Options
+// that have no DSN form, e.g. DialContext, HttpHeaders or a Location other than time.UTC, are left out.
+func (o *Options) FormatDSN() string {
+	dsn := url.URL{Scheme: o.scheme}
+	if dsn.Scheme == "" {
+		switch {
+		case o.Protocol == HTTP && o.TLS != nil:
+			dsn.Scheme = "https"
+		case o.Protocol == HTTP:
+			dsn.Scheme = "http"
+		default:
+			dsn.Scheme = "clickhouse"
+		}
+	}
+	params := url.Values{}
+	if len(o.Addr) == 1 && strings.HasPrefix(o.Addr[0], "unix://") {
+		dsn.Scheme, dsn.Path = "unix", strings.TrimPrefix(o.Addr[0], "unix://")
+		if o.Auth.Database != "" {
+			params.Set("database", o.Auth.Database)
+		}
+	} else {
+		dsn.Host = strings.Join(o.Addr, ",")
+		dsn.Path = "/" + o.Auth.Database
+	}
+	if o.Auth.Username != "" || o.Auth.Password != "" {
+		dsn.User = url.UserPassword(o.Auth.Username, o.Auth.Password)
+	}
+
+	for k, v := range o.Settings {
+		switch v := v.(type) {
+		case CustomSetting:
+			params.Set(k, v.Value)
+		default:
+			params.Set(k, fmt.Sprint(v))
+		}
+	}
+	setBool := func(name string, v bool) {
+		if v {
+			params.Set(name, strconv.FormatBool(v))
+		}
+	}
+	setInt := func(name string, v int) {
+		if v != 0 {
+			params.Set(name, strconv.Itoa(v))
+		}
+	}
+	setDuration := func(name string, v time.Duration) {
+		if v != 0 {
+			params.Set(name, v.String())
+		}
+	}
+	setBool("debug", o.Debug)
+	setBool("trace", o.Trace)
+	if o.Compression != nil {
+		if o.Compression.Method != CompressionNone {
+			params.Set("compress", o.Compression.Method.String())
+		}
+		// always given, a method alone is parsed with level 3
+		params.Set("compress_level", strconv.Itoa(o.Compression.Level))
+	}
+	setInt("max_compression_buffer", o.MaxCompressionBuffer)
+	setInt("max_string_size", o.MaxStringSize)
+	setInt("max_block_rows", o.MaxBlockRows)
+	setInt("read_buffer_size", o.ReadBufferSize)
+	if o.TimeLayout != "" {
+		params.Set("time_layout", o.TimeLayout)
+	}
+	if o.Location == time.UTC {
+		params.Set("use_server_time_zone", "false")
+	}
+	setDuration("dial_timeout", o.DialTimeout)
+	setInt("block_buffer_size", int(o.BlockBufferSize))
+	setDuration("read_timeout", o.ReadTimeout)
+	setInt("retry_reads", o.RetryReads)
+	setDuration("tcp_keepalive", o.TCPKeepAlive)
+	setDuration("write_timeout", o.WriteTimeout)
+	setDuration("server_idle_timeout", o.ServerIdleTimeout)
+	if o.ClientRevision != 0 {
+		params.Set("client_revision", strconv.FormatUint(o.ClientRevision, 10))
+	}
+	setBool("ignore_unknown_settings", o.IgnoreUnknownSettings)
+	if o.Proxy != nil {
+		params.Set("proxy", o.Proxy.String())
+	}
+	if o.TLS != nil {
+		params.Set("secure", "true")
+		setBool("skip_verify", o.TLS.InsecureSkipVerify)
+	}
+	switch o.ConnOpenStrategy {
+	case ConnOpenRoundRobin:
+		params.Set("connection_open_strategy", "round_robin")
+	case ConnOpenHealthy:
+		params.Set("connection_open_strategy", "healthy")
+	}
+	setInt("max_open_conns", o.MaxOpenConns)
+	setInt("max_idle_conns", o.MaxIdleConns)
+	setDuration("conn_max_lifetime", o.ConnMaxLifetime)
+	if o.QuotaKey != "" {
+		params.Set("quota_key", o.QuotaKey)
+	}
+	if o.ClientInfo.Name != "" {
+		params.Set("client_name", o.ClientInfo.Name)
+	}
+	if len(o.ClientInfo.Products) != 0 {
+		products := make([]string, 0, len(o.ClientInfo.Products))
+		for _, p := range o.ClientInfo.Products {
+			products = append(products, p.Name+"/"+p.Version)
+		}
+		params.Set("client_info_product", strings.Join(products, ","))
+	}
+	dsn.RawQuery = params.Encode()
+	return dsn.String()
+}
+
+// isValidDatabase reports whether name can be sent as the database of the handshake, which takes
+// any name the server does, only control characters are certainly a mistake.
+func isValidDatabase(name string) bool {
+	return !strings.ContainsFunc(name, unicode.IsControl)
+}
+
 // receive copy of Options, so we don't modify original - so its reusable
 func (o Options) setDefaults() *Options {
+	if o.ConnOpenStrategy == ConnOpenHealthy {
+		o.health = newHostHealth()
+	}
 	if len(o.Auth.Username) == 0 {
 		o.Auth.Username = "default"
 	}
@@ -363,6 +645,18 @@ func (o Options) setDefaults() *Options {
 	if o.MaxCompressionBuffer <= 0 {
 		o.MaxCompressionBuffer = 10485760
 	}
+	if o.MaxStringSize <= 0 {
+		o.MaxStringSize = proto.DefaultMaxStringSize
+	}
+	if o.MaxBlockRows <= 0 {
+		o.MaxBlockRows = proto.DefaultMaxBlockRows
+	}
+	if o.ReadBufferSize <= 0 {
+		o.ReadBufferSize = defaultReadBufferSize
+	}
+	if o.TimeLayout == "" {
+		o.TimeLayout = time.RFC3339
+	}
 	if o.Addr == nil || len(o.Addr) == 0 {
 		switch o.Protocol {
 		case Native:
@@ -18,21 +18,28 @@
 package clickhouse
 
 import (
+	"context"
 	"database/sql"
 	"io"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
+// rows streams the result of a query, the blocks are decoded as they arrive and queued on stream
+// at most BlockBufferSize ahead of the one being scanned, so a result is never read as a whole.
 type rows struct {
-	err       error
-	row       int
-	block     *proto.Block
-	totals    *proto.Block
-	errors    chan error
-	stream    chan *proto.Block
-	columns   []string
-	structMap *structMap
+	err        error
+	row        int
+	block      *proto.Block
+	totals     *proto.Block
+	extremes   *proto.Block
+	errors     chan error
+	stream     chan *proto.Block
+	columns    []string
+	structMap  *structMap
+	timeLayout string             // layout of date and time values scanned into a string
+	recycle    func(*proto.Block) // hands a fully read block back for reuse, may be nil
 }
 
 func (r *rows) Next() (result bool) {
@@ -49,19 +56,28 @@ next:
 		if r.stream == nil {
 			return false
 		}
-		select {
-		case err := <-r.errors:
-			if err != nil {
-				r.err = err
-				return false
-			}
-		case block := <-r.stream:
-			if block == nil {
-				return false
+		// the query goroutine queues its error before it closes the stream and only after the
+		// blocks read before it, so the rows that came before an exception are scanned first
+		block := <-r.stream
+		if block == nil {
+			select {
+			case err := <-r.errors:
+				if err != nil {
+					r.err = err
+				}
+			default:
 			}
-			if block.Packet == proto.ServerTotals {
-				r.row, r.block, r.totals = 0, nil, block
-				return false
+			return false
+		}
+		// the totals and extremes follow the data, they are kept aside until the stream ends
+		switch block.Packet {
+		case proto.ServerTotals:
+			r.totals = block
+		case proto.ServerExtremes:
+			r.extremes = block
+		default:
+			if r.recycle != nil {
+				r.recycle(r.block)
 			}
 			r.row, r.block = 0, block
 		}
@@ -75,7 +91,7 @@ func (r *rows) Scan(dest ...any) error {
 	if r.block == nil || (r.row == 0 && r.row >= r.block.Rows()) { // call without next when result is empty
 		return io.EOF
 	}
-	return scan(r.block, r.row, dest...)
+	return scan(r.block, r.row, r.timeLayout, dest...)
 }
 
 func (r *rows) ScanStruct(dest any) error {
@@ -90,51 +106,96 @@ func (r *rows) Totals(dest ...any) error {
 	if r.totals == nil {
 		return sql.ErrNoRows
 	}
-	return scan(r.totals, 1, dest...)
+	return scan(r.totals, 1, r.timeLayout, dest...)
 }
 
+// Extremes scans the minimum and the maximum of every column of a query run with extremes=1
+// into min and max, either of which may be left nil. It returns sql.ErrNoRows until the result
+// has been read to its end or when the query had no extremes.
+func (r *rows) Extremes(min, max []any) error {
+	if r.extremes == nil || r.extremes.Rows() != 2 {
+		return sql.ErrNoRows
+	}
+	if min != nil {
+		if err := scan(r.extremes, 1, r.timeLayout, min...); err != nil {
+			return err
+		}
+	}
+	if max != nil {
+		return scan(r.extremes, 2, r.timeLayout, max...)
+	}
+	return nil
+}
+
+var _ driver.ExtremesRows = (*rows)(nil)
+
 func (r *rows) Columns() []string {
 	return r.columns
 }
 
+// Close discards the blocks left unread until the query goroutine has read the response to its
+// end, so the connection goes back to the pool ready for the next query. A connection that fails
+// while doing so is closed rather than reused.
 func (r *rows) Close() error {
-	if r.errors == nil && r.stream == nil {
-		return r.err
-	}
-	active := 0
-	if r.errors != nil {
-		active++
-	}
-	if r.stream != nil {
-		active++
-	}
-	for {
+	// a closed channel is set to nil so it's not counted again while the other one is drained
+	stream, errors := r.stream, r.errors
+	for stream != nil || errors != nil {
 		select {
-		case _, ok := <-r.stream:
+		case _, ok := <-stream:
 			if !ok {
-				active--
-				if active == 0 {
-					return r.err
-				}
+				stream = nil
 			}
-		case err, ok := <-r.errors:
+		case err, ok := <-errors:
 			if err != nil {
 				r.err = err
 			}
 			if !ok {
-				active--
-				if active == 0 {
-					return r.err
-				}
+				errors = nil
 			}
 		}
 	}
+	return r.err
 }
 
 func (r *rows) Err() error {
 	return r.err
 }
 
+// blocks hands the blocks of the result over on a channel instead of scanning them row by row.
+// The first block is always sent so the columns are known even when the result is empty, later
+// empty blocks, the totals and the extremes are left out. The blocks aren't recycled, they belong
+// to the receiver.
+// A caller that stops reading has to cancel ctx, the rest of the result is then discarded.
+func (r *rows) blocks(ctx context.Context) <-chan driver.Block {
+	blocks := make(chan driver.Block)
+	go func() {
+		defer close(blocks)
+		send := func(block driver.Block) bool {
+			select {
+			case blocks <- block:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if send(driver.Block{Columns: r.block.Columns}) && r.stream != nil {
+			for block := range r.stream {
+				if block.Packet == proto.ServerTotals || block.Packet == proto.ServerExtremes || block.Rows() == 0 {
+					continue
+				}
+				if !send(driver.Block{Columns: block.Columns}) {
+					break
+				}
+			}
+		}
+		// the error may only arrive once the stream is closed, Close waits for it
+		if err := r.Close(); err != nil {
+			send(driver.Block{Err: err})
+		}
+	}()
+	return blocks
+}
+
 type row struct {
 	err  error
 	rows *rows
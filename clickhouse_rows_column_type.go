@@ -19,6 +19,7 @@ package clickhouse
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
@@ -50,13 +51,24 @@ func (c *columnType) DatabaseTypeName() string {
 func (r *rows) ColumnTypes() []driver.ColumnType {
 	types := make([]driver.ColumnType, 0, len(r.columns))
 	for i, c := range r.block.Columns {
-		_, nullable := c.(*column.Nullable)
 		types = append(types, &columnType{
 			name:     r.columns[i],
 			chType:   string(c.Type()),
-			nullable: nullable,
+			nullable: isNullable(c),
 			scanType: c.ScanType(),
 		})
 	}
 	return types
 }
+
+// isNullable reports whether a column can hold NULL values. LowCardinality(Nullable(T))
+// keeps its nullability in the dictionary, so it is detected from the type name.
+func isNullable(c column.Interface) bool {
+	switch c := c.(type) {
+	case *column.Nullable:
+		return true
+	case *column.LowCardinality:
+		return strings.HasPrefix(string(c.Type()), "LowCardinality(Nullable(")
+	}
+	return false
+}
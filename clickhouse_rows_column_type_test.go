@@ -0,0 +1,114 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnTypeNullable(t *testing.T) {
+	testCases := []struct {
+		chType   column.Type
+		nullable bool
+	}{
+		{"UInt64", false},
+		{"Nullable(String)", true},
+		{"Nullable(DateTime)", true},
+		{"LowCardinality(String)", false},
+		{"LowCardinality(Nullable(String))", true},
+		{"Array(Nullable(Int32))", false},
+	}
+
+	block := &proto.Block{}
+	for _, tc := range testCases {
+		require.NoError(t, block.AddColumn(string(tc.chType), tc.chType))
+	}
+	r := &rows{block: block, columns: block.ColumnsNames()}
+	std := &stdRows{rows: r}
+
+	types := r.ColumnTypes()
+	require.Len(t, types, len(testCases))
+	for i, tc := range testCases {
+		assert.Equal(t, tc.nullable, types[i].Nullable(), tc.chType)
+
+		nullable, ok := std.ColumnTypeNullable(i)
+		assert.True(t, ok, tc.chType)
+		assert.Equal(t, tc.nullable, nullable, tc.chType)
+	}
+}
+
+func TestColumnTypeLength(t *testing.T) {
+	testCases := []struct {
+		chType column.Type
+		length int64
+		ok     bool
+	}{
+		{"FixedString(32)", 32, true},
+		{"Nullable(FixedString(8))", 8, true},
+		{"LowCardinality(Nullable(FixedString(2)))", 2, true},
+		{"String", math.MaxInt64, true},
+		{"LowCardinality(String)", math.MaxInt64, true},
+		{"UInt64", 0, false},
+		{"Decimal(18, 4)", 0, false},
+		{"Array(FixedString(4))", 0, false},
+	}
+
+	block := &proto.Block{}
+	for _, tc := range testCases {
+		require.NoError(t, block.AddColumn(string(tc.chType), tc.chType))
+	}
+	std := &stdRows{rows: &rows{block: block, columns: block.ColumnsNames()}}
+	for i, tc := range testCases {
+		length, ok := std.ColumnTypeLength(i)
+		assert.Equal(t, tc.ok, ok, tc.chType)
+		assert.Equal(t, tc.length, length, tc.chType)
+	}
+}
+
+func TestColumnTypePrecisionScale(t *testing.T) {
+	testCases := []struct {
+		chType           column.Type
+		precision, scale int64
+		ok               bool
+	}{
+		{"Decimal(18, 4)", 18, 4, true},
+		{"Decimal(76, 38)", 76, 38, true},
+		{"Nullable(Decimal(10, 2))", 10, 2, true},
+		{"Array(Decimal(10, 2))", 0, 0, false},
+		{"Float64", 0, 0, false},
+		{"String", 0, 0, false},
+	}
+
+	block := &proto.Block{}
+	for _, tc := range testCases {
+		require.NoError(t, block.AddColumn(string(tc.chType), tc.chType))
+	}
+	std := &stdRows{rows: &rows{block: block, columns: block.ColumnsNames()}}
+	for i, tc := range testCases {
+		precision, scale, ok := std.ColumnTypePrecisionScale(i)
+		assert.Equal(t, tc.ok, ok, tc.chType)
+		assert.Equal(t, tc.precision, precision, tc.chType)
+		assert.Equal(t, tc.scale, scale, tc.chType)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"reflect"
 	"strings"
@@ -81,15 +82,10 @@ func (o *stdConnOpener) Connect(ctx context.Context) (_ driver.Conn, err error)
 		return nil, ErrAcquireConnNoAddress
 	}
 
-	for i := range o.opt.Addr {
-		var num int
-		switch o.opt.ConnOpenStrategy {
-		case ConnOpenInOrder:
-			num = i
-		case ConnOpenRoundRobin:
-			num = (int(connID) + i) % len(o.opt.Addr)
-		}
-		if conn, err = dialFunc(ctx, o.opt.Addr[num], connID, o.opt); err == nil {
+	for _, num := range o.opt.hostOrder(connID) {
+		conn, err = dialFunc(ctx, o.opt.Addr[num], connID, o.opt)
+		o.opt.dialed(ctx, o.opt.Addr[num], err)
+		if err == nil {
 			var debugf = func(format string, v ...any) {}
 			if o.opt.Debug {
 				if o.opt.Debugf != nil {
@@ -176,10 +172,16 @@ func OpenDB(opt *Options) *sql.DB {
 		})
 	}
 	o := opt.setDefaults()
-	return sql.OpenDB(&stdConnOpener{
+	db := sql.OpenDB(&stdConnOpener{
 		opt:    o,
 		debugf: debugf,
 	})
+	if o.Protocol == Native {
+		// an idle HTTP connection holds no socket of its own, its transport drops the idle ones and
+		// replacing it would only query the server time zone and version again
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime())
+	}
+	return db
 }
 
 type stdConnect interface {
@@ -194,7 +196,7 @@ type stdConnect interface {
 
 type stdDriver struct {
 	conn   stdConnect
-	commit func() error
+	batch  ldriver.Batch // the insert prepared in the transaction, sent on Commit
 	debugf func(format string, v ...any)
 }
 
@@ -221,7 +223,16 @@ func (std *stdDriver) Open(dsn string) (_ driver.Conn, err error) {
 
 var _ driver.Driver = (*stdDriver)(nil)
 
+// ResetSession is called by database/sql before a pooled connection is reused. Settings and
+// other query options travel with the context, so usually there is nothing to clear. A prepared
+// insert that was never committed leaves the connection in the middle of a query though, so it
+// is discarded the same way as a broken connection.
 func (std *stdDriver) ResetSession(ctx context.Context) error {
+	if std.batch != nil {
+		std.debugf("Resetting session because of an uncommitted batch")
+		std.batch = nil
+		return driver.ErrBadConn
+	}
 	if std.conn.isBad() {
 		std.debugf("Resetting session because connection is bad")
 		return driver.ErrBadConn
@@ -231,24 +242,41 @@ func (std *stdDriver) ResetSession(ctx context.Context) error {
 
 var _ driver.SessionResetter = (*stdDriver)(nil)
 
+// IsValid is called by database/sql as a connection goes back to the pool, one that failed
+// to write or read a response is dropped right away instead of failing the next query.
+func (std *stdDriver) IsValid() bool {
+	return !std.conn.isBad()
+}
+
+var _ driver.Validator = (*stdDriver)(nil)
+
 func (std *stdDriver) Ping(ctx context.Context) error { return std.conn.ping(ctx) }
 
 var _ driver.Pinger = (*stdDriver)(nil)
 
 func (std *stdDriver) Begin() (driver.Tx, error) { return std, nil }
+
+// BeginTx starts a batch scope. ClickHouse has no ACID transactions, so only the
+// default isolation level is accepted to avoid giving a false sense of guarantees.
 func (std *stdDriver) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		return nil, ErrTxIsolationNotSupported
+	}
+	if opts.ReadOnly {
+		return nil, ErrTxReadOnlyNotSupported
+	}
 	return std, nil
 }
 
 func (std *stdDriver) Commit() error {
-	if std.commit == nil {
+	if std.batch == nil {
 		return nil
 	}
 	defer func() {
-		std.commit = nil
+		std.batch = nil
 	}()
 
-	if err := std.commit(); err != nil {
+	if err := std.batch.Send(); err != nil {
 		if isConnBrokenError(err) {
 			std.debugf("Commit got EOF error: resetting connection")
 			return driver.ErrBadConn
@@ -259,8 +287,22 @@ func (std *stdDriver) Commit() error {
 	return nil
 }
 
+// Flush sends the rows appended so far to the insert prepared in the transaction, the server
+// writes them right away while the transaction stays open for more rows until Commit. It's
+// reached through sql.Conn.Raw. Over HTTP the rows are only sent on Commit.
+func (std *stdDriver) Flush() error {
+	if std.batch == nil {
+		return nil
+	}
+	if err := std.batch.Flush(); err != nil {
+		std.debugf("Flush error: %v\n", err)
+		return err
+	}
+	return nil
+}
+
 func (std *stdDriver) Rollback() error {
-	std.commit = nil
+	std.batch = nil
 	std.conn.close()
 	return nil
 }
@@ -275,6 +317,22 @@ func (std *stdDriver) ExecContext(ctx context.Context, query string, args []driv
 	if options := queryOptions(ctx); options.async.ok {
 		return driver.RowsAffected(0), std.conn.asyncInsert(ctx, query, options.async.wait, rebind(args)...)
 	}
+	var (
+		affected, written int64
+		events            = queryOptions(ctx).events
+	)
+	// inserts report the rows written in progress packets, other statements in the profile info
+	ctx = Context(ctx, WithProgress(func(p *Progress) {
+		written += int64(p.WroteRows)
+		if events.progress != nil {
+			events.progress(p)
+		}
+	}), WithProfileInfo(func(p *ProfileInfo) {
+		affected += int64(p.Rows)
+		if events.profileInfo != nil {
+			events.profileInfo(p)
+		}
+	}))
 	if err := std.conn.exec(ctx, query, rebind(args)...); err != nil {
 		if isConnBrokenError(err) {
 			std.debugf("ExecContext got a fatal error, resetting connection: %v\n", err)
@@ -283,9 +341,24 @@ func (std *stdDriver) ExecContext(ctx context.Context, query string, args []driv
 		std.debugf("ExecContext error: %v\n", err)
 		return nil, err
 	}
-	return driver.RowsAffected(0), nil
+	if written > 0 {
+		affected = written
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+// Exec runs statements that return no rows, such as DDL. The affected row count is taken from
+// the progress or profile info sent by the server and is 0 when the server does not report it.
+func (std *stdDriver) Exec(query string, args []driver.Value) (driver.Result, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return std.ExecContext(context.Background(), query, named)
 }
 
+var _ driver.Execer = (*stdDriver)(nil) //nolint:staticcheck
+
 func (std *stdDriver) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	r, err := std.conn.query(ctx, func(*connect, error) {}, query, rebind(args)...)
 	if isConnBrokenError(err) {
@@ -307,6 +380,10 @@ func (std *stdDriver) Prepare(query string) (driver.Stmt, error) {
 }
 
 func (std *stdDriver) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if insertSelectRe.MatchString(query) {
+		// the server reads the rows itself, there is no batch to append to
+		return &stdStmt{std: std, query: query}, nil
+	}
 	batch, err := std.conn.prepareBatch(ctx, query, ldriver.PrepareBatchOptions{}, func(*connect, error) {}, func(context.Context) (*connect, error) { return nil, nil })
 	if err != nil {
 		if isConnBrokenError(err) {
@@ -316,7 +393,7 @@ func (std *stdDriver) PrepareContext(ctx context.Context, query string) (driver.
 		std.debugf("PrepareContext error: %v\n", err)
 		return nil, err
 	}
-	std.commit = batch.Send
+	std.batch = batch
 	return &stdBatch{
 		batch:  batch,
 		debugf: std.debugf,
@@ -340,7 +417,22 @@ type stdBatch struct {
 	debugf func(format string, v ...any)
 }
 
-func (s *stdBatch) NumInput() int { return -1 }
+// NumInput reports the number of columns being inserted, so database/sql can reject a wrong
+// number of arguments before they reach the batch.
+func (s *stdBatch) NumInput() int {
+	switch b := s.batch.(type) {
+	case *batch:
+		return len(b.block.Columns)
+	case *httpBatch:
+		return len(b.block.Columns)
+	}
+	return -1
+}
+
+// Exec appends a row to the batch of the transaction. Its RowsAffected of 1 is a placeholder
+// counting the appended row: the rows are written on Commit, and database/sql has no way to report
+// the written rows of the progress packets from there. Statements run outside a transaction, see
+// stdDriver.ExecContext, report the rows the server wrote.
 func (s *stdBatch) Exec(args []driver.Value) (driver.Result, error) {
 	values := make([]any, 0, len(args))
 	for _, v := range args {
@@ -350,7 +442,7 @@ func (s *stdBatch) Exec(args []driver.Value) (driver.Result, error) {
 		s.debugf("[batch][exec] append error: %v", err)
 		return nil, err
 	}
-	return driver.RowsAffected(0), nil
+	return driver.RowsAffected(1), nil
 }
 
 func (s *stdBatch) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
@@ -370,6 +462,30 @@ func (s *stdBatch) Query(args []driver.Value) (driver.Rows, error) {
 
 func (s *stdBatch) Close() error { return nil }
 
+// stdStmt runs a prepared statement that takes no client data, e.g. INSERT INTO ... SELECT, as a whole on each Exec.
+type stdStmt struct {
+	std   *stdDriver
+	query string
+}
+
+func (s *stdStmt) NumInput() int { return -1 }
+
+func (s *stdStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.std.Exec(s.query, args)
+}
+
+func (s *stdStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.std.ExecContext(ctx, s.query, args)
+}
+
+var _ driver.StmtExecContext = (*stdStmt)(nil)
+
+func (s *stdStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("only Exec method supported for INSERT INTO ... SELECT")
+}
+
+func (s *stdStmt) Close() error { return nil }
+
 type stdRows struct {
 	rows   *rows
 	debugf func(format string, v ...any)
@@ -390,28 +506,49 @@ func (r *stdRows) ColumnTypeDatabaseTypeName(idx int) string {
 }
 
 func (r *stdRows) ColumnTypeNullable(idx int) (nullable, ok bool) {
-	_, ok = r.rows.block.Columns[idx].(*column.Nullable)
-	return ok, true
+	return isNullable(r.rows.block.Columns[idx]), true
 }
 
 func (r *stdRows) ColumnTypePrecisionScale(idx int) (precision, scale int64, ok bool) {
 	switch col := r.rows.block.Columns[idx].(type) {
 	case *column.Decimal:
 		return col.Precision(), col.Scale(), true
-	case interface{ Base() column.Interface }:
-		switch col := col.Base().(type) {
-		case *column.Decimal:
+	case *column.Nullable:
+		// only Nullable, an Array of decimals isn't a decimal itself
+		if col, ok := col.Base().(*column.Decimal); ok {
 			return col.Precision(), col.Scale(), true
 		}
 	}
 	return 0, 0, false
 }
 
+// ColumnTypeLength reports N for FixedString(N) and math.MaxInt64 for String, which has no bound,
+// looking through Nullable and LowCardinality. Decimal goes through ColumnTypePrecisionScale instead,
+// other types have no length.
+func (r *stdRows) ColumnTypeLength(idx int) (length int64, ok bool) {
+	t := string(r.rows.block.Columns[idx].Type())
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if strings.HasPrefix(t, wrapper) && strings.HasSuffix(t, ")") {
+			t = t[len(wrapper) : len(t)-1]
+		}
+	}
+	switch {
+	case t == "String":
+		return math.MaxInt64, true
+	case strings.HasPrefix(t, "FixedString("):
+		if _, err := fmt.Sscanf(t, "FixedString(%d)", &length); err == nil {
+			return length, true
+		}
+	}
+	return 0, false
+}
+
 var _ driver.Rows = (*stdRows)(nil)
 var _ driver.RowsNextResultSet = (*stdRows)(nil)
 var _ driver.RowsColumnTypeDatabaseTypeName = (*stdRows)(nil)
 var _ driver.RowsColumnTypeNullable = (*stdRows)(nil)
 var _ driver.RowsColumnTypePrecisionScale = (*stdRows)(nil)
+var _ driver.RowsColumnTypeLength = (*stdRows)(nil)
 
 func (r *stdRows) Next(dest []driver.Value) error {
 	if len(r.rows.block.Columns) != len(dest) {
@@ -446,15 +583,18 @@ func (r *stdRows) Next(dest []driver.Value) error {
 	return io.EOF
 }
 
+// HasNextResultSet reports the totals and then the extremes, which are read as result sets of
+// their own once the rows are.
 func (r *stdRows) HasNextResultSet() bool {
-	return r.rows.totals != nil
+	return r.rows.totals != nil || r.rows.extremes != nil
 }
 
 func (r *stdRows) NextResultSet() error {
 	switch {
 	case r.rows.totals != nil:
-		r.rows.block = r.rows.totals
-		r.rows.totals = nil
+		r.rows.row, r.rows.block, r.rows.totals = 0, r.rows.totals, nil
+	case r.rows.extremes != nil:
+		r.rows.row, r.rows.block, r.rows.extremes = 0, r.rows.extremes, nil
 	default:
 		return io.EOF
 	}
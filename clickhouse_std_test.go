@@ -0,0 +1,536 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	ldriver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdBeginTx(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        driver.TxOptions
+		expectedErr error
+	}{
+		{"default", driver.TxOptions{}, nil},
+		{"explicit default isolation", driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelDefault)}, nil},
+		{"read committed", driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelReadCommitted)}, ErrTxIsolationNotSupported},
+		{"serializable", driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable)}, ErrTxIsolationNotSupported},
+		{"read only", driver.TxOptions{ReadOnly: true}, ErrTxReadOnlyNotSupported},
+		{"read only snapshot", driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSnapshot), ReadOnly: true}, ErrTxIsolationNotSupported},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			std := &stdDriver{debugf: func(string, ...any) {}}
+			tx, err := std.BeginTx(context.Background(), tc.opts)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, tx)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, tx)
+		})
+	}
+}
+
+type fakeStdConnect struct {
+	stdConnect
+	bad bool
+}
+
+func (c *fakeStdConnect) isBad() bool { return c.bad }
+
+func TestStdResetSession(t *testing.T) {
+	testCases := []struct {
+		name        string
+		bad         bool
+		batch       ldriver.Batch
+		expectedErr error
+	}{
+		{"clean", false, nil, nil},
+		{"bad connection", true, nil, driver.ErrBadConn},
+		{"uncommitted batch", false, &batch{}, driver.ErrBadConn},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			std := &stdDriver{
+				conn:   &fakeStdConnect{bad: tc.bad},
+				batch:  tc.batch,
+				debugf: func(string, ...any) {},
+			}
+			err := std.ResetSession(context.Background())
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Nil(t, std.batch)
+		})
+	}
+}
+
+func TestStdIsValid(t *testing.T) {
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("id", "UInt64"))
+	require.NoError(t, block.Append(uint64(1)))
+	open := func(t *testing.T, srv *fakeServer) *stdDriver {
+		conn, err := (&stdConnOpener{
+			opt:    (&Options{DialContext: srv.dial}).setDefaults(),
+			debugf: func(string, ...any) {},
+		}).Connect(context.Background())
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return conn.(*stdDriver)
+	}
+
+	t.Run("clean", func(t *testing.T) {
+		std := open(t, newFakeServer().data(block).endOfStream())
+		rows, err := std.QueryContext(context.Background(), "SELECT id FROM t", nil)
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+		assert.True(t, std.IsValid())
+	})
+
+	t.Run("out of sync", func(t *testing.T) {
+		srv := newFakeServer().data(block)
+		srv.script.PutByte(99) // no such packet
+		std := open(t, srv)
+		rows, err := std.QueryContext(context.Background(), "SELECT id FROM t", nil)
+		require.NoError(t, err)
+		dest := make([]driver.Value, 1)
+		require.NoError(t, rows.Next(dest))
+		assert.ErrorContains(t, rows.Next(dest), "unexpected packet 99")
+		rows.Close()
+		assert.False(t, std.IsValid())
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		std := open(t, newFakeServer())
+		conn := std.conn.(*connect)
+		require.NoError(t, conn.conn.Close())
+		_, err := std.ExecContext(context.Background(), "INSERT INTO t SELECT 1", nil)
+		require.Error(t, err)
+		assert.False(t, conn.closed, "the connection is left to database/sql to close")
+		assert.False(t, std.IsValid())
+	})
+}
+
+func TestStdExecDDL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		srv      *fakeServer
+		affected int64
+	}{
+		{"no profile info", newFakeServer().progress(0, 0).endOfStream(), 0},
+		{"profile info", newFakeServer().progress(0, 0).profileInfo(3).endOfStream(), 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := OpenDB(&Options{DialContext: tc.srv.dial})
+			defer db.Close()
+
+			result, err := db.Exec("CREATE TABLE t (id UInt64) ENGINE = Memory")
+			require.NoError(t, err)
+			affected, err := result.RowsAffected()
+			require.NoError(t, err)
+			assert.Equal(t, tc.affected, affected)
+			assert.Contains(t, string(tc.srv.sent()), "CREATE TABLE t")
+		})
+	}
+
+	t.Run("Execer", func(t *testing.T) {
+		srv := newFakeServer().profileInfo(2).endOfStream()
+		conn, err := Connector(&Options{DialContext: srv.dial}).Connect(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		result, err := conn.(driver.Execer).Exec("DROP TABLE t", nil) //nolint:staticcheck
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, affected)
+	})
+}
+
+func TestStdExecRowsAffected(t *testing.T) {
+	t.Run("insert select", func(t *testing.T) {
+		var progress int
+		srv := newFakeServer().progress(0, 2).progress(0, 3).profileInfo(1).endOfStream()
+		db := OpenDB(&Options{DialContext: srv.dial})
+		defer db.Close()
+
+		ctx := Context(context.Background(), WithProgress(func(*Progress) { progress++ }))
+		result, err := db.ExecContext(ctx, "INSERT INTO t SELECT number FROM numbers(5)")
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, affected)
+		assert.Equal(t, 2, progress, "the caller's progress callback still runs")
+		_, err = result.LastInsertId()
+		assert.Error(t, err)
+	})
+
+	t.Run("prepared insert", func(t *testing.T) {
+		header := &proto.Block{}
+		require.NoError(t, header.AddColumn("id", "UInt64"))
+		srv := newFakeServer().data(header).endOfStream()
+		db := OpenDB(&Options{DialContext: srv.dial})
+		defer db.Close()
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		stmt, err := tx.Prepare("INSERT INTO t")
+		require.NoError(t, err)
+		result, err := stmt.Exec(uint64(1))
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, affected)
+		_, err = result.LastInsertId()
+		assert.Error(t, err)
+		require.NoError(t, tx.Commit())
+	})
+}
+
+func TestStdFlush(t *testing.T) {
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("name", "String"))
+	srv := newFakeServer().data(header).endOfStream()
+	db := OpenDB(&Options{DialContext: srv.dial})
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	tx, err := conn.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	stmt, err := tx.Prepare("INSERT INTO t")
+	require.NoError(t, err)
+	flush := func() error {
+		return conn.Raw(func(c any) error {
+			return c.(interface{ Flush() error }).Flush()
+		})
+	}
+
+	_, err = stmt.Exec("first-flushed-row")
+	require.NoError(t, err)
+	require.NoError(t, flush())
+	assert.Eventually(t, func() bool {
+		return strings.Contains(string(srv.sent()), "first-flushed-row")
+	}, time.Second, 10*time.Millisecond, "the flushed row reaches the server before the commit")
+
+	_, err = stmt.Exec("second-committed-row")
+	require.NoError(t, err)
+	assert.NotContains(t, string(srv.sent()), "second-committed-row")
+	require.NoError(t, tx.Commit())
+	assert.Contains(t, string(srv.sent()), "second-committed-row")
+	assert.NoError(t, flush(), "nothing is left to flush once the transaction is committed")
+}
+
+func TestStdPrepareInsertSelect(t *testing.T) {
+	srv := newFakeServer().progress(0, 5).endOfStream()
+	db := OpenDB(&Options{DialContext: srv.dial})
+	defer db.Close()
+
+	stmt, err := db.Prepare("INSERT INTO t SELECT number FROM numbers(5)")
+	require.NoError(t, err)
+	defer stmt.Close()
+	result, err := stmt.Exec()
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, affected)
+	assert.Contains(t, string(srv.sent()), "INSERT INTO t SELECT number FROM numbers(5)")
+	assert.NotContains(t, string(srv.sent()), "VALUES")
+}
+
+func TestStdScanSmallInts(t *testing.T) {
+	block := &proto.Block{}
+	for _, c := range []struct{ name, chType string }{{"i8", "Int8"}, {"i16", "Int16"}, {"u8", "UInt8"}, {"u16", "UInt16"}} {
+		require.NoError(t, block.AddColumn(c.name, column.Type(c.chType)))
+	}
+	require.NoError(t, block.Append(int8(math.MinInt8), int16(math.MinInt16), uint8(math.MaxUint8), uint16(math.MaxUint16)))
+	require.NoError(t, block.Append(int8(math.MaxInt8), int16(math.MaxInt16), uint8(1), uint16(0)))
+	srv := newFakeServer().data(block).endOfStream()
+
+	db := OpenDB(&Options{DialContext: srv.dial})
+	defer db.Close()
+
+	rows, err := db.Query("SELECT i8, i16, u8, u16 FROM t")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var got [][4]int64
+	for rows.Next() {
+		var row [4]int64
+		require.NoError(t, rows.Scan(&row[0], &row[1], &row[2], &row[3]))
+		got = append(got, row)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, [][4]int64{
+		{math.MinInt8, math.MinInt16, math.MaxUint8, math.MaxUint16},
+		{math.MaxInt8, math.MaxInt16, 1, 0},
+	}, got)
+}
+
+func TestStdScanTime(t *testing.T) {
+	value := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("t", "DateTime"))
+	require.NoError(t, block.Append(value))
+	// both queries run on the same connection, which the script answers in turn
+	srv := newFakeServer().data(block).endOfStream().data(block).endOfStream()
+
+	db := OpenDB(&Options{DialContext: srv.dial})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var t0 time.Time
+	require.NoError(t, db.QueryRow("SELECT t").Scan(&t0))
+	assert.True(t, value.Equal(t0))
+
+	var text string
+	require.NoError(t, db.QueryRow("SELECT t").Scan(&text))
+	assert.Equal(t, "2024-03-01T12:30:00Z", text)
+}
+
+func TestStdBatchNumInput(t *testing.T) {
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("a", "UInt64"))
+	require.NoError(t, block.AddColumn("b", "String"))
+
+	testCases := []struct {
+		name     string
+		batch    ldriver.Batch
+		expected int
+	}{
+		{"native", &batch{block: block}, 2},
+		{"http", &httpBatch{block: block}, 2},
+		{"unknown", nil, -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt := &stdBatch{batch: tc.batch}
+			assert.Equal(t, tc.expected, stmt.NumInput())
+		})
+	}
+}
+
+func TestStdPrepareWrongNumberOfValues(t *testing.T) {
+	server := &fakeHTTPServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	db := OpenDB(&Options{
+		Addr:     []string{strings.TrimPrefix(ts.URL, "http://")},
+		Protocol: HTTP,
+	})
+	defer db.Close()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	stmt, err := tx.Prepare("INSERT INTO t")
+	require.NoError(t, err)
+	_, err = stmt.Exec(uint64(1), "extra")
+	require.EqualError(t, err, "sql: expected 1 arguments, got 2")
+	_, err = stmt.Exec(uint64(1))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, []uint64{1}, server.inserted)
+}
+
+func TestStdServerErrorMidQuery(t *testing.T) {
+	block := func(ids ...uint64) *proto.Block {
+		block := &proto.Block{}
+		require.NoError(t, block.AddColumn("id", "UInt64"))
+		for _, id := range ids {
+			require.NoError(t, block.Append(id))
+		}
+		return block
+	}
+	// servers are handed out in order, one per dialed connection
+	dialer := func(servers ...*fakeServer) (func(context.Context, string) (net.Conn, error), *atomic.Int64) {
+		var dials atomic.Int64
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			n := int(dials.Add(1)) - 1
+			return servers[min(n, len(servers)-1)].dial(ctx, addr)
+		}, &dials
+	}
+	query := func(db *sql.DB) ([]uint64, error) {
+		rows, err := db.Query("SELECT id FROM t")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var ids []uint64
+		for rows.Next() {
+			var id uint64
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, rows.Err()
+	}
+
+	t.Run("exception", func(t *testing.T) {
+		// the exception ends the response, the second query is answered on the same connection
+		srv := newFakeServer().data(block(1)).raise(CodeMemoryLimitExceeded, "Memory limit exceeded").
+			data(block(2)).endOfStream()
+		dial, dials := dialer(srv)
+		db := OpenDB(&Options{DialContext: dial})
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+
+		_, err := query(db)
+		assert.True(t, IsErrorCode(err, CodeMemoryLimitExceeded), err)
+		ids, err := query(db)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{2}, ids)
+		assert.EqualValues(t, 1, dials.Load())
+	})
+
+	t.Run("unreadable block", func(t *testing.T) {
+		// a block that can't be decoded leaves the rest of it unread on the connection
+		corrupt := newFakeServer().data(block(1, 2, 3))
+		corrupt.script.Buf = bytes.Replace(corrupt.script.Buf, []byte("UInt64"), []byte("UInt99"), 1)
+		broken := newFakeServer().data(block())
+		broken.script.PutRaw(corrupt.script.Buf)
+		dial, dials := dialer(broken, newFakeServer().data(block(2)).endOfStream())
+		db := OpenDB(&Options{DialContext: dial})
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+
+		_, err := query(db)
+		require.Error(t, err)
+		ids, err := query(db)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{2}, ids)
+		assert.EqualValues(t, 2, dials.Load(), "the connection is replaced")
+	})
+}
+
+func TestStdExtremes(t *testing.T) {
+	newBlock := func(values ...uint64) *proto.Block {
+		block := &proto.Block{}
+		require.NoError(t, block.AddColumn("n", "UInt64"))
+		for _, v := range values {
+			require.NoError(t, block.Append(v))
+		}
+		return block
+	}
+	srv := newFakeServer().
+		data(newBlock(3, 1, 7)).
+		block(proto.ServerExtremes, newBlock(1, 7)).
+		endOfStream()
+	db := OpenDB(&Options{DialContext: srv.dial})
+	defer db.Close()
+
+	rows, err := db.Query("SELECT n FROM t SETTINGS extremes = 1")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var sets [][]uint64
+	for {
+		var set []uint64
+		for rows.Next() {
+			var n uint64
+			require.NoError(t, rows.Scan(&n))
+			set = append(set, n)
+		}
+		sets = append(sets, set)
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, [][]uint64{{3, 1, 7}, {1, 7}}, sets, "the extremes are the next result set")
+}
+
+func TestStdHTTPKeepsIdleConnections(t *testing.T) {
+	var (
+		server    = &fakeHTTPServer{}
+		timezones atomic.Int32
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "SELECT timezone()" {
+			timezones.Add(1)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	db := OpenDB(&Options{
+		Addr:              []string{strings.TrimPrefix(ts.URL, "http://")},
+		Protocol:          HTTP,
+		ServerIdleTimeout: 50 * time.Millisecond,
+	})
+	defer db.Close()
+
+	require.NoError(t, db.Ping())
+	// long enough for the connection cleaner of sql.DB, which runs at most once a second
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, db.Ping())
+	assert.Zero(t, db.Stats().MaxIdleTimeClosed)
+	assert.EqualValues(t, 1, timezones.Load(), "the connection is reused rather than dialed again")
+}
+
+func TestStdTimeLayout(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC)
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("formatted", "DateTime64(9, 'UTC')"))
+	require.NoError(t, block.AddColumn("value", "DateTime64(9, 'UTC')"))
+	require.NoError(t, block.Append(at, at))
+	srv := newFakeServer().data(block).endOfStream()
+	db := OpenDB(&Options{DialContext: srv.dial, TimeLayout: "2006-01-02"})
+	defer db.Close()
+
+	// the driver hands database/sql a time.Time, TimeLayout only applies to the native Rows.Scan
+	var (
+		formatted string
+		value     time.Time
+	)
+	require.NoError(t, db.QueryRow("SELECT formatted, value FROM t").Scan(&formatted, &value))
+	assert.Equal(t, at.Format(time.RFC3339Nano), formatted)
+	assert.True(t, at.Equal(value))
+}
@@ -0,0 +1,174 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	t.Run("native api", func(t *testing.T) {
+		srv := newFakeServer().pong()
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		require.NotNil(t, conn)
+		defer conn.Close()
+
+		require.NoError(t, conn.Ping(context.Background()))
+		version, err := conn.ServerVersion()
+		require.NoError(t, err)
+		assert.Equal(t, "ClickHouse", version.Name)
+	})
+
+	t.Run("database/sql connector", func(t *testing.T) {
+		srv := newFakeServer().pong()
+		conn, err := Connector(&Options{DialContext: srv.dial}).Connect(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, conn)
+		defer conn.Close()
+
+		require.NoError(t, conn.(*stdDriver).Ping(context.Background()))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	listen := func(t *testing.T, srv *fakeServer) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go srv.serve(conn)
+			}
+		}()
+		return listener.Addr().String()
+	}
+	unreachable := func(t *testing.T) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		require.NoError(t, listener.Close())
+		return addr
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, Validate("clickhouse://"+listen(t, newFakeServer())+"/default"))
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		assert.NoError(t, Validate("clickhouse://"+unreachable(t)+","+listen(t, newFakeServer())))
+	})
+
+	t.Run("bad credentials", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.exception = &Exception{Code: CodeAuthenticationFailed, Message: "wrong password"}
+		err := Validate("clickhouse://alice:wrong@" + listen(t, srv))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: authenticate with")
+		assert.Contains(t, err.Error(), `authentication of user "alice" failed`)
+		assert.True(t, IsErrorCode(err, CodeAuthenticationFailed))
+	})
+
+	t.Run("unreachable host", func(t *testing.T) {
+		addr := unreachable(t)
+		err := Validate("clickhouse://" + addr + "?dial_timeout=1s")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: dial "+addr)
+	})
+
+	t.Run("unreachable proxy", func(t *testing.T) {
+		err := Validate("clickhouse://" + listen(t, newFakeServer()) + "?dial_timeout=1s&proxy=socks5://" + unreachable(t))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: dial ")
+	})
+
+	t.Run("not a clickhouse server", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			conn.Close()
+		}()
+		err = Validate("clickhouse://" + listener.Addr().String() + "?dial_timeout=1s")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: handshake with")
+	})
+
+	t.Run("http", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Code: 516. DB::Exception: alice: Authentication failed", http.StatusUnauthorized)
+		}))
+		defer ts.Close()
+		err := Validate(ts.URL)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: authenticate with")
+
+		addr := unreachable(t)
+		err = Validate("http://" + addr + "?dial_timeout=1s")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: dial "+addr)
+	})
+
+	t.Run("malformed dsn", func(t *testing.T) {
+		err := Validate("clickhouse://127.0.0.1/?retry_reads=-1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clickhouse [validate]: parse dsn")
+	})
+}
+
+func TestIdleReconnect(t *testing.T) {
+	var (
+		dials int
+		srv   = newFakeServer().endOfStream().endOfStream()
+	)
+	conn, err := Open(&Options{
+		DialContext: func(ctx context.Context, addr string) (net.Conn, error) {
+			dials++
+			return srv.dial(ctx, addr)
+		},
+		ServerIdleTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+	require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+	assert.Equal(t, 1, dials, "a briefly idle connection is reused")
+
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, conn.Exec(ctx, "SELECT 1"), "the server may have closed it, so a fresh one is dialed")
+	assert.Equal(t, 2, dials)
+}
@@ -35,6 +35,11 @@ const (
 )
 
 type ClientInfo struct {
+	// Name replaces the name the client introduces itself with, shown as client_name in
+	// system.query_log, e.g. to tell services on the same host apart. When empty it's built
+	// from Products, the driver version and the Go runtime.
+	Name string
+
 	Products []struct {
 		Name    string
 		Version string
@@ -44,6 +49,9 @@ type ClientInfo struct {
 }
 
 func (o ClientInfo) String() string {
+	if o.Name != "" {
+		return o.Name
+	}
 	var s strings.Builder
 
 	info := o
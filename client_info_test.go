@@ -59,6 +59,14 @@ func TestClientInfoString(t *testing.T) {
 			// e.g. grafana-datasource/0.1.1 clickhouse-go/2.5.1 (lv:go/1.19.5; os:darwin)
 			fmt.Sprintf("grafana-datasource/0.1.1 %s (%s)", expectedClientProduct, expectedDefaultMeta),
 		},
+		"name": {
+			ClientInfo{
+				Name:     "billing-exporter",
+				Products: []struct{ Name, Version string }{{Name: "grafana", Version: "6.1"}},
+				comment:  []string{"database/sql"},
+			},
+			"billing-exporter",
+		},
 		"additional products with comment": {
 			ClientInfo{
 				Products: []struct {
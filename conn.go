@@ -18,44 +18,105 @@
 package clickhouse
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2/resources"
 	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
 
 	"github.com/ClickHouse/ch-go/compress"
 	chproto "github.com/ClickHouse/ch-go/proto"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
-func dial(ctx context.Context, addr string, num int, opt *Options) (*connect, error) {
+// defaultReadBufferSize is the size of the buffer the protocol reader reads the connection into.
+const defaultReadBufferSize = 128 * 1024
+
+// dialError is a failure to establish the connection, before the handshake, be it by the
+// dialer, a custom DialContext or a proxy.
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string { return e.err.Error() }
+func (e *dialError) Unwrap() error { return e.err }
+
+// shortReader reads at most max bytes at a time.
+type shortReader struct {
+	reader io.Reader
+	max    int
+}
+
+func (r *shortReader) Read(p []byte) (int, error) {
+	if len(p) > r.max {
+		p = p[:r.max]
+	}
+	return r.reader.Read(p)
+}
+
+func dial(ctx context.Context, addr string, num int, opt *Options) (_ *connect, err error) {
 	var (
-		err    error
 		conn   net.Conn
 		debugf = func(format string, v ...any) {}
+		host   = addr
 	)
+	revision, err := clientRevision(opt)
+	if err != nil {
+		return nil, err
+	}
+	compression := CompressionNone
+	if opt.Compression != nil {
+		switch opt.Compression.Method {
+		case CompressionLZ4, CompressionZSTD, CompressionNone:
+			compression = opt.Compression.Method
+		default:
+			return nil, fmt.Errorf("unsupported compression method for native protocol")
+		}
+	}
 	switch {
 	case opt.DialContext != nil:
-		conn, err = opt.DialContext(ctx, addr)
+		if conn, err = dialContext(ctx, addr, opt); err == nil {
+			if err = setKeepAlive(conn, opt.TCPKeepAlive); err != nil {
+				conn.Close()
+			}
+		}
 	default:
+		network := "tcp"
+		if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+			network, addr = "unix", path
+		}
+		dialer := &net.Dialer{Timeout: opt.DialTimeout, KeepAlive: opt.TCPKeepAlive}
 		switch {
+		case opt.Proxy != nil && network == "tcp":
+			conn, err = dialProxy(ctx, addr, dialer, opt)
 		case opt.TLS != nil:
-			conn, err = tls.DialWithDialer(&net.Dialer{Timeout: opt.DialTimeout}, "tcp", addr, opt.TLS)
+			conn, err = (&tls.Dialer{NetDialer: dialer, Config: opt.TLS}).DialContext(ctx, network, addr)
 		default:
-			conn, err = net.DialTimeout("tcp", addr, opt.DialTimeout)
+			conn, err = dialer.DialContext(ctx, network, addr)
 		}
 	}
 	if err != nil {
-		return nil, err
+		return nil, &dialError{err: err}
 	}
+	defer func() {
+		// a failed handshake leaves nothing that would close the connection
+		if err != nil {
+			conn.Close()
+		}
+	}()
 	if opt.Debug {
 		if opt.Debugf != nil {
 			debugf = opt.Debugf
@@ -63,25 +124,22 @@ func dial(ctx context.Context, addr string, num int, opt *Options) (*connect, er
 			debugf = log.New(os.Stdout, fmt.Sprintf("[clickhouse][conn=%d][%s]", num, conn.RemoteAddr()), 0).Printf
 		}
 	}
-	compression := CompressionNone
-	if opt.Compression != nil {
-		switch opt.Compression.Method {
-		case CompressionLZ4, CompressionZSTD, CompressionNone:
-			compression = opt.Compression.Method
-		default:
-			return nil, fmt.Errorf("unsupported compression method for native protocol")
-		}
+	var source io.Reader = conn
+	if opt.Debug && opt.Trace {
+		source = &traceReader{reader: conn, debugf: debugf}
 	}
 
 	var (
 		connect = &connect{
 			id:                   num,
+			addr:                 host,
+			server:               ServerVersion{Addr: host},
 			opt:                  opt,
+			database:             opt.Auth.Database,
 			conn:                 conn,
 			debugf:               debugf,
 			buffer:               new(chproto.Buffer),
-			reader:               chproto.NewReader(conn),
-			revision:             ClientTCPProtocolVersion,
+			revision:             revision,
 			structMap:            &structMap{},
 			compression:          compression,
 			connectedAt:          time.Now(),
@@ -89,13 +147,26 @@ func dial(ctx context.Context, addr string, num int, opt *Options) (*connect, er
 			readTimeout:          opt.ReadTimeout,
 			blockBufferSize:      opt.BlockBufferSize,
 			maxCompressionBuffer: opt.MaxCompressionBuffer,
+			maxStringSize:        opt.MaxStringSize,
 		}
 	)
-	if err := connect.handshake(opt.Auth.Database, opt.Auth.Username, opt.Auth.Password); err != nil {
+	if opt.Metrics != nil {
+		source = &countingReader{reader: source, n: &connect.bytesReceived}
+	}
+	switch {
+	case opt.ReadBufferSize > defaultReadBufferSize:
+		// the protocol reader uses a buffered reader that is large enough as is, rather than wrapping it again
+		source = bufio.NewReaderSize(source, opt.ReadBufferSize)
+	case opt.ReadBufferSize > 0 && opt.ReadBufferSize < defaultReadBufferSize:
+		// the buffer of the protocol reader can't be made smaller, its reads of the connection can
+		source = &shortReader{reader: source, max: opt.ReadBufferSize}
+	}
+	connect.reader = chproto.NewReader(source)
+	if err = connect.handshake(opt.Auth.Database, opt.Auth.Username, opt.Auth.Password); err != nil {
 		return nil, err
 	}
-	if connect.revision >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_ADDENDUM {
-		if err := connect.sendAddendum(); err != nil {
+	if connect.supports(proto.DBMS_MIN_PROTOCOL_VERSION_WITH_ADDENDUM) {
+		if err = connect.sendAddendum(); err != nil {
 			return nil, err
 		}
 	}
@@ -107,17 +178,85 @@ func dial(ctx context.Context, addr string, num int, opt *Options) (*connect, er
 	return connect, nil
 }
 
+// dialContext dials addr with Options.DialContext, bounded by DialTimeout like the default dialer
+// so an address that hangs fails over to the next one instead of using up ctx.
+func dialContext(ctx context.Context, addr string, opt *Options) (net.Conn, error) {
+	if opt.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.DialTimeout)
+		defer cancel()
+	}
+	return opt.DialContext(ctx, addr)
+}
+
+// dialProxy connects to addr through the SOCKS5 proxy of opt, forward dials the proxy itself. With
+// TLS the handshake is made with the server once the proxy has connected to it.
+func dialProxy(ctx context.Context, addr string, forward *net.Dialer, opt *Options) (net.Conn, error) {
+	dialer, err := proxy.FromURL(opt.Proxy, forward)
+	if err != nil {
+		return nil, err
+	}
+	if opt.DialTimeout > 0 {
+		// the timeout of forward covers the connection to the proxy only
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.DialTimeout)
+		defer cancel()
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("proxy %s: unsupported scheme %q", opt.Proxy.Redacted(), opt.Proxy.Scheme)
+	}
+	conn, err := contextDialer.DialContext(ctx, "tcp", addr)
+	if err != nil || opt.TLS == nil {
+		return conn, err
+	}
+	config := opt.TLS
+	if config.ServerName == "" {
+		// verified against the server, not the proxy
+		host, _, _ := net.SplitHostPort(addr)
+		config = config.Clone()
+		config.ServerName = host
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// setKeepAlive applies Options.TCPKeepAlive to connections made by a custom DialContext,
+// with the same meaning as net.Dialer.KeepAlive. Zero leaves the connection as it is.
+func setKeepAlive(conn net.Conn, period time.Duration) error {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok || period == 0 {
+		return nil
+	}
+	if period < 0 {
+		return tcp.SetKeepAlive(false)
+	}
+	if err := tcp.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tcp.SetKeepAlivePeriod(period)
+}
+
 // https://github.com/ClickHouse/ClickHouse/blob/master/src/Client/Connection.cpp
 type connect struct {
 	id                   int
+	addr                 string // the Options.Addr entry the connection was dialed to, after failover
 	opt                  *Options
+	database             string // the current database of the session, changed by a USE query
 	conn                 net.Conn
 	debugf               func(format string, v ...any)
 	server               ServerVersion
 	closed               bool
+	bad                  bool // a write failed or the server answered out of turn, the stream is out of sync
 	buffer               *chproto.Buffer
 	reader               *chproto.Reader
 	released             bool
+	releasedAt           time.Time // when the connection was last put back in the pool
+	deadline             time.Time // set with setDeadline, zero when none
 	revision             uint64
 	structMap            *structMap
 	compression          CompressionMethod
@@ -126,29 +265,120 @@ type connect struct {
 	readTimeout          time.Duration
 	blockBufferSize      uint8
 	maxCompressionBuffer int
+	maxStringSize        int
+	bytesSent            uint64
+	bytesReceived        uint64 // only counted when Options.Metrics is set
+	// responded is set once a packet of the response to the last query sent was read
+	responded bool
+	// secret is the range of c.buffer holding the password of a buffered ClientHello, it's masked
+	// in the trace of the next flush
+	secret [2]int
+	// blocks holds decoded blocks the reader is done with, their columns are reused by readData
+	blocks sync.Pool
+	// defaults are the settings set with Conn.SetSetting, shared by all connections of a pool
+	defaults *defaultSettings
+	// busy is set while a request waits for or streams its response, see begin
+	busy atomic.Bool
 }
 
-func (c *connect) settings(querySettings Settings) []proto.Setting {
+// begin marks the connection busy for the request op until end is called. A second request
+// started meanwhile, e.g. a ping while rows are streamed, fails rather than reading packets
+// that answer the first one.
+func (c *connect) begin(op string) error {
+	if !c.busy.CompareAndSwap(false, true) {
+		c.debugf("[%s] connection is busy", op)
+		return errors.Wrap(ErrConnBusy, op)
+	}
+	return nil
+}
+
+func (c *connect) end() {
+	c.busy.Store(false)
+}
+
+// defaultSettings holds the settings applied to every query between Options.Settings and the
+// settings of the query context.
+type defaultSettings struct {
+	mu       sync.RWMutex
+	settings Settings
+}
+
+func (d *defaultSettings) set(name string, value any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.settings == nil {
+		d.settings = Settings{}
+	}
+	d.settings[name] = value
+}
+
+func (d *defaultSettings) clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.settings = nil
+}
+
+func (d *defaultSettings) copyTo(settings Settings) {
+	if d == nil {
+		return
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for k, v := range d.settings {
+		settings[k] = v
+	}
+}
+
+// httpOnlySettings are parameters of the HTTP interface rather than settings, the native server
+// rejects them as unknown.
+var httpOnlySettings = map[string]struct{}{
+	"buffer_size":       {},
+	"compress":          {},
+	"decompress":        {},
+	"default_format":    {},
+	"session_check":     {},
+	"session_id":        {},
+	"session_timeout":   {},
+	"wait_end_of_query": {},
+}
+
+// settings merges Options.Settings, the settings set on the connection and the query settings, in
+// that order of precedence. maxExecutionTime, when positive, is sent unless one of them sets it.
+func (c *connect) settings(querySettings Settings, maxExecutionTime int) []proto.Setting {
 	settingToProtoSetting := func(k string, v any) proto.Setting {
 		isCustom := false
 		if cv, ok := v.(CustomSetting); ok {
 			v = cv.Value
 			isCustom = true
+		} else {
+			v = settingValue(v)
 		}
 
 		return proto.Setting{
-			Key:       k,
-			Value:     v,
-			Important: !isCustom,
+			Key:   k,
+			Value: v,
+			// the server logs a setting it doesn't know that isn't important and goes on without it
+			Important: !isCustom && !c.opt.IgnoreUnknownSettings,
 			Custom:    isCustom,
 		}
 	}
 
-	settings := make([]proto.Setting, 0, len(c.opt.Settings)+len(querySettings))
+	merged := make(Settings, len(c.opt.Settings)+len(querySettings))
 	for k, v := range c.opt.Settings {
-		settings = append(settings, settingToProtoSetting(k, v))
+		merged[k] = v
 	}
+	c.defaults.copyTo(merged)
 	for k, v := range querySettings {
+		merged[k] = v
+	}
+	if _, ok := merged["max_execution_time"]; !ok && maxExecutionTime > 0 {
+		merged["max_execution_time"] = maxExecutionTime
+	}
+	settings := make([]proto.Setting, 0, len(merged))
+	for k, v := range merged {
+		if _, ok := httpOnlySettings[k]; ok && c.opt.IgnoreUnknownSettings {
+			continue
+		}
 		settings = append(settings, settingToProtoSetting(k, v))
 	}
 	return settings
@@ -156,7 +386,11 @@ func (c *connect) settings(querySettings Settings) []proto.Setting {
 
 func (c *connect) isBad() bool {
 	switch {
-	case c.closed:
+	case c.closed, c.bad:
+		return true
+	case c.database != c.opt.Auth.Database && (c.opt.Auth.Database != "" || c.database != "default"):
+		// switched to another database by USE, the next user of the pool expects the DSN one;
+		// without one in the DSN the session starts on the server's default database
 		return true
 	}
 
@@ -197,7 +431,7 @@ func (c *connect) exception() error {
 	if err := e.Decode(c.reader); err != nil {
 		return err
 	}
-	c.debugf("[exception] %s", e.Error())
+	c.debugf("[exception] %s", e.Verbose())
 	return &e
 }
 
@@ -260,7 +494,7 @@ func (c *connect) sendData(block *proto.Block, name string) error {
 }
 
 func (c *connect) readData(ctx context.Context, packet byte, compressible bool) (*proto.Block, error) {
-	if _, err := c.reader.Str(); err != nil {
+	if _, err := proto.ReadString(c.reader, c.maxStringSize); err != nil {
 		c.debugf("[read data] str error: %v", err)
 		return nil, err
 	}
@@ -271,33 +505,88 @@ func (c *connect) readData(ctx context.Context, packet byte, compressible bool)
 
 	opts := queryOptions(ctx)
 	location := c.server.Timezone
+	if c.opt.Location != nil {
+		location = c.opt.Location
+	}
 	if opts.userLocation != nil {
 		location = opts.userLocation
 	}
 
-	block := proto.Block{Timezone: location}
+	block, _ := c.blocks.Get().(*proto.Block)
+	if block == nil || block.Timezone != location {
+		block = &proto.Block{Timezone: location}
+	}
+	block.MaxStringSize, block.MaxRows = c.maxStringSize, c.opt.MaxBlockRows
 	if err := block.Decode(c.reader, c.revision); err != nil {
 		c.debugf("[read data] decode error: %v", err)
 		return nil, err
 	}
 	block.Packet = packet
 	c.debugf("[read data] compression=%q. block: columns=%d, rows=%d", c.compression, len(block.Columns), block.Rows())
-	return &block, nil
+	return block, nil
 }
 
+// setDeadline sets the read and write deadline of the connection, the zero time clears it. flush
+// restores it after bounding a write by Options.WriteTimeout.
+func (c *connect) setDeadline(deadline time.Time) {
+	c.deadline = deadline
+	c.conn.SetDeadline(deadline)
+}
+
+// flush writes everything buffered since the last flush with a single write. Packets are
+// accumulated in c.buffer and flushed once complete, so the number of writes doesn't grow
+// with the number of values encoded. Options.WriteTimeout applies to each flush on its own,
+// within the deadline set with setDeadline.
 func (c *connect) flush() error {
 	if len(c.buffer.Buf) == 0 {
 		// Nothing to flush.
 		return nil
 	}
+	if c.opt.WriteTimeout > 0 {
+		deadline := time.Now().Add(c.opt.WriteTimeout)
+		if !c.deadline.IsZero() && c.deadline.Before(deadline) {
+			deadline = c.deadline
+		}
+		c.conn.SetWriteDeadline(deadline)
+		defer c.conn.SetWriteDeadline(c.deadline)
+	}
+	if c.opt.Debug && c.opt.Trace {
+		dump := c.buffer.Buf
+		if from, to := c.secret[0], c.secret[1]; to > from {
+			dump = append([]byte(nil), dump...)
+			for i := from; i < to; i++ {
+				dump[i] = '*'
+			}
+		}
+		c.debugf("[write] %d bytes\n%s", len(dump), hex.Dump(dump))
+	}
+	c.secret = [2]int{}
 	n, err := c.conn.Write(c.buffer.Buf)
+	c.bytesSent += uint64(n)
 	if err != nil {
+		// the server may have got part of a packet, whatever is sent next won't make sense to it
+		c.bad = true
 		return errors.Wrap(err, "write")
 	}
 	if n != len(c.buffer.Buf) {
+		c.bad = true
 		return errors.New("wrote less than expected")
 	}
 
 	c.buffer.Reset()
 	return nil
 }
+
+// traceReader hex dumps everything read from the server.
+type traceReader struct {
+	reader io.Reader
+	debugf func(format string, v ...any)
+}
+
+func (t *traceReader) Read(p []byte) (int, error) {
+	n, err := t.reader.Read(p)
+	if n > 0 {
+		t.debugf("[read] %d bytes\n%s", n, hex.Dump(p[:n]))
+	}
+	return n, err
+}
@@ -23,17 +23,28 @@ import (
 )
 
 func (c *connect) asyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
+	if err := c.begin("asyncInsert"); err != nil {
+		return err
+	}
+	defer c.end()
 	options := queryOptions(ctx)
 	{
-		options.settings["async_insert"] = 1
-		options.settings["wait_for_async_insert"] = 0
+		// the settings may be shared with other contexts through WithSettings, so they're copied
+		settings := make(Settings, len(options.settings)+2)
+		for k, v := range options.settings {
+			settings[k] = v
+		}
+		settings["async_insert"] = 1
+		settings["wait_for_async_insert"] = 0
 		if wait {
-			options.settings["wait_for_async_insert"] = 1
+			// the server answers once the data is flushed to the table, process waits for its end of stream
+			settings["wait_for_async_insert"] = 1
 		}
+		options.settings = settings
 	}
 
 	if len(args) > 0 {
-		queryParamsProtocolSupport := c.revision >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_PARAMETERS
+		queryParamsProtocolSupport := c.supports(proto.DBMS_MIN_PROTOCOL_VERSION_WITH_PARAMETERS)
 		var err error
 		query, err = bindQueryOrAppendParameters(queryParamsProtocolSupport, &options, query, c.server.Timezone, args...)
 		if err != nil {
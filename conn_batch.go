@@ -32,8 +32,15 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
-var splitInsertRe = regexp.MustCompile(`(?i)\sVALUES\s*\(`)
-var columnMatch = regexp.MustCompile(`INSERT INTO .+\s\((?P<Columns>.+)\)$`)
+// splitInsertRe cuts the VALUES clause, with or without values, off an insert so the column list can be matched
+var splitInsertRe = regexp.MustCompile(`(?i)\sVALUES\s*(\(|$)`)
+var columnMatch = regexp.MustCompile(`(?i)INSERT INTO .+\s\((?P<Columns>.+)\)$`)
+
+// insertSelectRe matches an INSERT INTO ... SELECT, which the server runs without any data from the client
+var insertSelectRe = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s.+?\s(SELECT|WITH)\s`)
+
+// insertSettingsRe matches a SETTINGS clause following the table and column list of an insert
+var insertSettingsRe = regexp.MustCompile(`(?i)\sSETTINGS\s+\w+\s*=.*$`)
 
 func (c *connect) prepareBatch(ctx context.Context, query string, opts driver.PrepareBatchOptions, release func(*connect, error), acquire func(context.Context) (*connect, error)) (driver.Batch, error) {
 	//defer func() {
@@ -41,8 +48,16 @@ func (c *connect) prepareBatch(ctx context.Context, query string, opts driver.Pr
 	//		fmt.Printf("panic occurred on %d:\n", c.num)
 	//	}
 	//}()
+	if insertSelectRe.MatchString(query) {
+		release(c, nil)
+		return nil, ErrInsertSelectBatch
+	}
+	if err := c.begin("prepareBatch"); err != nil {
+		return nil, err
+	}
 	query = splitInsertRe.Split(query, -1)[0]
-	colMatch := columnMatch.FindStringSubmatch(query)
+	// the settings clause is sent along with the query, but must not get in the way of the column list
+	colMatch := columnMatch.FindStringSubmatch(insertSettingsRe.ReplaceAllString(query, ""))
 	var columns []string
 	if len(colMatch) == 2 {
 		columns = strings.Split(colMatch[1], ",")
@@ -57,10 +72,11 @@ func (c *connect) prepareBatch(ctx context.Context, query string, opts driver.Pr
 	}
 	options := queryOptions(ctx)
 	if deadline, ok := ctx.Deadline(); ok {
-		c.conn.SetDeadline(deadline)
-		defer c.conn.SetDeadline(time.Time{})
+		c.setDeadline(deadline)
+		defer c.setDeadline(time.Time{})
 	}
 	if err := c.sendQuery(query, &options); err != nil {
+		c.end()
 		release(c, err)
 		return nil, err
 	}
@@ -69,11 +85,13 @@ func (c *connect) prepareBatch(ctx context.Context, query string, opts driver.Pr
 		block, err = c.firstBlock(ctx, onProcess)
 	)
 	if err != nil {
+		c.end()
 		release(c, err)
 		return nil, err
 	}
 	// resort batch to specified columns
 	if err = block.SortColumns(columns); err != nil {
+		c.end()
 		return nil, err
 	}
 
@@ -111,6 +129,7 @@ type batch struct {
 func (b *batch) release(err error) {
 	if !b.released {
 		b.released = true
+		b.conn.end()
 		b.connRelease(b.conn, err)
 	}
 }
@@ -154,6 +173,8 @@ func (b *batch) Append(v ...any) error {
 func (b *batch) appendRowsBlocks(r *rows) error {
 	var lastReadLock *proto.Block
 	var blockNum int
+	// the blocks become the batch's, reusing one for the next block read would change the rows being sent
+	r.recycle = nil
 
 	for r.Next() {
 		if lastReadLock == nil { // make sure the first block is logged
@@ -260,6 +281,11 @@ func (b *batch) resetConnection() (err error) {
 	if b.conn, err = b.connAcquire(b.ctx); err != nil {
 		return err
 	}
+	if err = b.conn.begin("batch"); err != nil {
+		// the connection is in use elsewhere, it's handed back without ending that use
+		b.connRelease(b.conn, err)
+		return err
+	}
 
 	defer func() {
 		b.released = false
@@ -267,8 +293,8 @@ func (b *batch) resetConnection() (err error) {
 
 	options := queryOptions(b.ctx)
 	if deadline, ok := b.ctx.Deadline(); ok {
-		b.conn.conn.SetDeadline(deadline)
-		defer b.conn.conn.SetDeadline(time.Time{})
+		b.conn.setDeadline(deadline)
+		defer b.conn.setDeadline(time.Time{})
 	}
 
 	if err = b.conn.sendQuery(b.query, &options); err != nil {
@@ -296,8 +322,24 @@ func (b *batch) Flush() error {
 			return err
 		}
 	}
+	if err := b.ctx.Err(); err != nil {
+		// the insert goes away with its connection, the server drops the blocks flushed so far
+		b.err = err
+		b.release(err)
+		return err
+	}
 	if b.block.Rows() != 0 {
-		if err := b.conn.sendData(b.block, ""); err != nil {
+		stopCW := contextWatchdog(b.ctx, func() {
+			_ = b.conn.conn.Close()
+		})
+		err := b.conn.sendData(b.block, "")
+		stopCW()
+		if err != nil {
+			if ctxErr := b.ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			b.err = err
+			b.release(err)
 			return err
 		}
 	}
@@ -0,0 +1,435 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCounter counts the writes made to the underlying connection, i.e. the syscalls on a real socket.
+type writeCounter struct {
+	net.Conn
+	writes *atomic.Int64
+}
+
+func (w writeCounter) Write(p []byte) (int, error) {
+	w.writes.Add(1)
+	return w.Conn.Write(p)
+}
+
+// insert runs a batch insert of rows small rows against a fake server and returns the number of writes
+// made after the handshake.
+func insert(tb testing.TB, rows int) int64 {
+	header := &proto.Block{}
+	require.NoError(tb, header.AddColumn("id", "UInt64"))
+	require.NoError(tb, header.AddColumn("name", "String"))
+
+	var (
+		writes atomic.Int64
+		srv    = newFakeServer().data(header).endOfStream()
+		dial   = func(ctx context.Context, addr string) (net.Conn, error) {
+			conn, err := srv.dial(ctx, addr)
+			return writeCounter{Conn: conn, writes: &writes}, err
+		}
+	)
+	conn, err := Open(&Options{DialContext: dial})
+	require.NoError(tb, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	batch, err := conn.PrepareBatch(ctx, "INSERT INTO t")
+	require.NoError(tb, err)
+	for i := 0; i < rows; i++ {
+		require.NoError(tb, batch.Append(uint64(i), "row"))
+	}
+	require.NoError(tb, batch.Send())
+	return writes.Load() - 2 // client hello and addendum
+}
+
+func TestBatchWritesPerPacket(t *testing.T) {
+	// the query, data block and end of data packets are each written once, regardless of the row count
+	assert.EqualValues(t, 3, insert(t, 1))
+	assert.EqualValues(t, 3, insert(t, 10_000))
+}
+
+func BenchmarkBatchSmallRows(b *testing.B) {
+	var writes int64
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writes += insert(b, 10_000)
+	}
+	b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+}
+
+func TestBatchColumnOrder(t *testing.T) {
+	// the server returns the header in table order, whatever the order of the insert column list
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("a", "UInt64"))
+	require.NoError(t, header.AddColumn("b", "String"))
+
+	for _, query := range []string{
+		"INSERT INTO t (b, a)",
+		"INSERT INTO t (`b`, \"a\") VALUES",
+		"insert into t (b, a) values (?, ?)",
+		"INSERT INTO t (b, a) SETTINGS async_insert=1, wait_for_async_insert=0 VALUES",
+	} {
+		t.Run(query, func(t *testing.T) {
+			srv := newFakeServer().data(header).endOfStream()
+			conn, err := Open(&Options{DialContext: srv.dial})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			b, err := conn.PrepareBatch(context.Background(), query)
+			require.NoError(t, err)
+			require.NoError(t, b.Append("x", uint64(1)))
+			require.NoError(t, b.Append("y", uint64(2)))
+
+			block := b.(*batch).block
+			assert.Equal(t, []string{"b", "a"}, block.ColumnsNames())
+			var (
+				colA uint64
+				colB string
+			)
+			require.NoError(t, block.Columns[0].ScanRow(&colB, 1))
+			require.NoError(t, block.Columns[1].ScanRow(&colA, 1))
+			assert.Equal(t, "y", colB)
+			assert.EqualValues(t, 2, colA)
+			require.NoError(t, b.Send())
+		})
+	}
+}
+
+// slowWriter delays every write once enabled, simulating a link that makes steady but slow progress.
+type slowWriter struct {
+	net.Conn
+	delay *atomic.Int64
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Duration(w.delay.Load()))
+	return w.Conn.Write(p)
+}
+
+func TestBatchWriteTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	send := func(delay time.Duration) error {
+		header := &proto.Block{}
+		require.NoError(t, header.AddColumn("id", "UInt64"))
+		require.NoError(t, header.AddColumn("name", "String"))
+
+		var (
+			slow atomic.Int64
+			srv  = newFakeServer().data(header).endOfStream()
+			dial = func(ctx context.Context, addr string) (net.Conn, error) {
+				conn, err := srv.dial(ctx, addr)
+				return slowWriter{Conn: conn, delay: &slow}, err
+			}
+		)
+		conn, err := Open(&Options{DialContext: dial, WriteTimeout: timeout, MaxCompressionBuffer: 1})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		batch, err := conn.PrepareBatch(context.Background(), "INSERT INTO t")
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, batch.Append(uint64(i), "row"))
+		}
+		slow.Store(int64(delay))
+		return batch.Send()
+	}
+
+	t.Run("steady progress", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, send(timeout/2))
+		// every chunk fits in the timeout while the commit as a whole doesn't
+		assert.Greater(t, time.Since(start), timeout)
+	})
+
+	t.Run("stalled write", func(t *testing.T) {
+		assert.Error(t, send(2*timeout))
+	})
+}
+
+func TestBatchResetConnectionBusy(t *testing.T) {
+	busy := &connect{debugf: func(string, ...any) {}}
+	busy.busy.Store(true)
+	var released []*connect
+	b := &batch{
+		ctx:      context.Background(),
+		released: true,
+		connAcquire: func(context.Context) (*connect, error) {
+			return busy, nil
+		},
+		connRelease: func(c *connect, err error) {
+			assert.ErrorIs(t, err, ErrConnBusy)
+			released = append(released, c)
+		},
+	}
+	assert.ErrorIs(t, b.Flush(), ErrConnBusy)
+	assert.Equal(t, []*connect{busy}, released, "the acquired connection goes back to the pool")
+	assert.True(t, busy.busy.Load(), "the use of the connection elsewhere isn't ended")
+}
+
+func TestBatchBool(t *testing.T) {
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("flag", "Bool"))
+	srv := newFakeServer().data(header).endOfStream()
+
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	b, err := conn.PrepareBatch(context.Background(), "INSERT INTO t")
+	require.NoError(t, err)
+	require.NoError(t, b.Append(true))
+	require.NoError(t, b.Append(false))
+	require.NoError(t, b.Send())
+
+	// the column header followed by the values as single bytes
+	assert.True(t, bytes.Contains(srv.sent(), []byte("\x04flag\x04Bool\x00\x01\x00")))
+}
+
+func TestBatchSettingsClause(t *testing.T) {
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("a", "UInt64"))
+
+	srv := newFakeServer().data(header).endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	b, err := conn.PrepareBatch(context.Background(), "INSERT INTO t SETTINGS async_insert=1 VALUES")
+	require.NoError(t, err)
+	require.NoError(t, b.Append(uint64(1)))
+	require.NoError(t, b.Send())
+	assert.Contains(t, string(srv.sent()), "INSERT INTO t SETTINGS async_insert=1 VALUES")
+}
+
+func TestBatchColumnar(t *testing.T) {
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("id", "Int64"))
+	require.NoError(t, header.AddColumn("name", "String"))
+
+	t.Run("whole columns", func(t *testing.T) {
+		srv := newFakeServer().data(header).endOfStream()
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		b, err := conn.PrepareBatch(context.Background(), "INSERT INTO t")
+		require.NoError(t, err)
+		require.NoError(t, b.Column(0).Append([]int64{1, 2, 3}))
+		require.NoError(t, b.Column(1).Append([]string{"a", "b", "c"}))
+		assert.Equal(t, 3, b.Rows())
+		require.NoError(t, b.Send())
+	})
+
+	t.Run("mismatched lengths", func(t *testing.T) {
+		srv := newFakeServer().data(header).endOfStream()
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		b, err := conn.PrepareBatch(context.Background(), "INSERT INTO t")
+		require.NoError(t, err)
+		require.NoError(t, b.Column(0).Append([]int64{1, 2, 3}))
+		require.NoError(t, b.Column(1).Append([]string{"a"}))
+		err = b.Send()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mismatched len of columns")
+	})
+}
+
+// BenchmarkBatchInsert compares appending a million rows one at a time with appending them as whole columns.
+func BenchmarkBatchInsert(b *testing.B) {
+	const rows = 1_000_000
+	header := &proto.Block{}
+	require.NoError(b, header.AddColumn("id", "Int64"))
+	require.NoError(b, header.AddColumn("name", "String"))
+
+	ids := make([]int64, rows)
+	names := make([]string, rows)
+	for i := range ids {
+		ids[i], names[i] = int64(i), "row"
+	}
+
+	run := func(b *testing.B, fill func(driver.Batch) error) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			srv := newFakeServer().data(header).endOfStream()
+			conn, err := Open(&Options{DialContext: srv.dial})
+			require.NoError(b, err)
+			batch, err := conn.PrepareBatch(context.Background(), "INSERT INTO t")
+			require.NoError(b, err)
+			require.NoError(b, fill(batch))
+			require.NoError(b, batch.Send())
+			require.NoError(b, conn.Close())
+		}
+	}
+
+	b.Run("rows", func(b *testing.B) {
+		run(b, func(batch driver.Batch) error {
+			for i := range ids {
+				if err := batch.Append(ids[i], names[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	b.Run("columns", func(b *testing.B) {
+		run(b, func(batch driver.Batch) error {
+			if err := batch.Column(0).Append(ids); err != nil {
+				return err
+			}
+			return batch.Column(1).Append(names)
+		})
+	})
+}
+
+func TestBatchInsertSelect(t *testing.T) {
+	for query, insertSelect := range map[string]bool{
+		"INSERT INTO t SELECT * FROM s":                       true,
+		"insert into t (a, b)\nselect a, b from s":            true,
+		"INSERT INTO t WITH 1 AS x SELECT x":                  true,
+		"INSERT INTO t":                                       false,
+		"INSERT INTO t (a, b) VALUES":                         false,
+		"INSERT INTO t SETTINGS async_insert=1 VALUES (?, ?)": false,
+		"INSERT INTO selected (a)":                            false,
+	} {
+		assert.Equal(t, insertSelect, insertSelectRe.MatchString(query), query)
+	}
+
+	srv := newFakeServer()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.PrepareBatch(context.Background(), "INSERT INTO t SELECT * FROM s")
+	assert.ErrorIs(t, err, ErrInsertSelectBatch)
+	assert.Equal(t, 0, conn.Stats().Open, "the connection is released")
+}
+
+func TestBatchFlushCancel(t *testing.T) {
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("id", "UInt64"))
+
+	srv := newFakeServer().data(header).endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batch, err := conn.PrepareBatch(ctx, "INSERT INTO t")
+	require.NoError(t, err)
+	require.NoError(t, batch.Append(uint64(1)))
+	require.NoError(t, batch.Flush())
+	sent := len(srv.sent())
+
+	cancel()
+	require.NoError(t, batch.Append(uint64(2)))
+	assert.ErrorIs(t, batch.Flush(), context.Canceled)
+	assert.ErrorIs(t, batch.Send(), context.Canceled, "the insert is aborted rather than committed")
+	assert.Len(t, srv.sent(), sent, "no block is sent once the context is done")
+	assert.Equal(t, 0, conn.Stats().Idle, "the connection of the aborted insert isn't reused")
+}
+
+func TestAsyncInsertAck(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	// with wait_for_async_insert=1 the server answers once the data is flushed to the table
+	deferred := func(srv *fakeServer) (*fakeServer, driver.Conn, time.Time) {
+		ack, start := make(chan struct{}), time.Now()
+		time.AfterFunc(delay, func() { close(ack) })
+		srv.awaiting(ack).progress(0, 1).endOfStream()
+		srv.endOfStream() // the query run after the insert
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return srv, conn, start
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		header := &proto.Block{}
+		require.NoError(t, header.AddColumn("n", "UInt64"))
+		_, conn, start := deferred(newFakeServer().data(header))
+		batch, err := conn.PrepareBatch(context.Background(), "INSERT INTO t SETTINGS async_insert=1, wait_for_async_insert=1 VALUES")
+		require.NoError(t, err)
+		require.NoError(t, batch.Append(uint64(1)))
+		require.NoError(t, batch.Send())
+		assert.GreaterOrEqual(t, time.Since(start), delay, "the commit waits for the acknowledgement")
+
+		require.NoError(t, conn.Exec(context.Background(), "SELECT 1"), "the acknowledgement is read to its end")
+		assert.Equal(t, 1, conn.Stats().Idle)
+	})
+
+	t.Run("AsyncInsert", func(t *testing.T) {
+		srv, conn, start := deferred(newFakeServer())
+		settings := Settings{"max_threads": 2}
+		ctx := Context(context.Background(), WithSettings(settings))
+		require.NoError(t, conn.AsyncInsert(ctx, "INSERT INTO t VALUES (1)", true))
+		assert.GreaterOrEqual(t, time.Since(start), delay, "the insert waits for the acknowledgement")
+		assert.Contains(t, string(srv.sent()), "wait_for_async_insert")
+		assert.Equal(t, Settings{"max_threads": 2}, settings, "the settings of the context are left untouched")
+
+		require.NoError(t, conn.Exec(context.Background(), "SELECT 1"), "the acknowledgement is read to its end")
+		assert.Equal(t, 1, conn.Stats().Idle)
+	})
+}
+
+func TestBatchAppendRows(t *testing.T) {
+	const numBlocks, numRows = 20, 100
+	source, err := Open(&Options{DialContext: newFakeServer().blocks(t, numBlocks, numRows).dial})
+	require.NoError(t, err)
+	defer source.Close()
+
+	header := &proto.Block{}
+	require.NoError(t, header.AddColumn("id", "UInt64"))
+	require.NoError(t, header.AddColumn("name", "String"))
+	require.NoError(t, header.AddColumn("code", "FixedString(4)"))
+	srv := newFakeServer().data(header).endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	rows, err := source.Query(ctx, "SELECT id, name, code FROM t")
+	require.NoError(t, err)
+	batch, err := conn.PrepareBatch(ctx, "INSERT INTO t")
+	require.NoError(t, err)
+	// each block is flushed while the next one is read, which must not reuse the one being sent
+	require.NoError(t, batch.Append(rows))
+	require.NoError(t, batch.Send())
+
+	sent := srv.sent()
+	for id := 0; id < numBlocks*numRows; id++ {
+		name := fmt.Sprintf("row %d", id)
+		// as encoded in the String column, after its length
+		require.True(t, bytes.Contains(sent, append([]byte{byte(len(name))}, name...)), "%s is sent", name)
+	}
+}
@@ -19,14 +19,20 @@ package clickhouse
 
 import (
 	"context"
-	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"strings"
 	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
 func (c *connect) exec(ctx context.Context, query string, args ...any) error {
+	if err := c.begin("exec"); err != nil {
+		return err
+	}
+	defer c.end()
 	var (
 		options                    = queryOptions(ctx)
-		queryParamsProtocolSupport = c.revision >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_PARAMETERS
+		queryParamsProtocolSupport = c.supports(proto.DBMS_MIN_PROTOCOL_VERSION_WITH_PARAMETERS)
 		body, err                  = bindQueryOrAppendParameters(queryParamsProtocolSupport, &options, query, c.server.Timezone, args...)
 	)
 	if err != nil {
@@ -37,11 +43,46 @@ func (c *connect) exec(ctx context.Context, query string, args ...any) error {
 	defer c.conn.SetReadDeadline(time.Time{})
 	// context level deadlines override any read deadline
 	if deadline, ok := ctx.Deadline(); ok {
-		c.conn.SetDeadline(deadline)
-		defer c.conn.SetDeadline(time.Time{})
+		c.setDeadline(deadline)
+		defer c.setDeadline(time.Time{})
 	}
+	var (
+		onProcess = options.onProcess()
+		metrics   = c.startQueryMetrics(ctx, body, options.queryID)
+	)
+	metrics.observe(onProcess)
 	if err := c.sendQuery(body, &options); err != nil {
+		metrics.end(err)
 		return err
 	}
-	return c.process(ctx, options.onProcess())
+	// a long INSERT ... SELECT may not send anything for a while, the watchdog interrupts the
+	// read so a cancelled context doesn't wait for the next packet
+	stopCW := contextWatchdog(ctx, func() {
+		c.conn.SetReadDeadline(time.Now())
+	})
+	err = c.process(ctx, onProcess)
+	stopCW()
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		// process has cancelled the query and closed the connection, the interrupted read may
+		// have stopped inside a packet so it can't be drained
+		err = &OpError{Op: "exec", Err: ctxErr}
+	}
+	metrics.end(err)
+	if database, ok := useDatabase(body); ok && err == nil {
+		c.database = database
+	}
+	return err
+}
+
+// useDatabase returns the database a USE query switches the session to.
+func useDatabase(query string) (string, bool) {
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "USE") {
+		return "", false
+	}
+	database := fields[1]
+	if len(database) > 1 && (database[0] == '`' || database[0] == '"') && database[len(database)-1] == database[0] {
+		database = database[1 : len(database)-1]
+	}
+	return database, true
 }
@@ -25,6 +25,19 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
+// clientRevision returns the protocol revision advertised in the client hello, ClientTCPProtocolVersion
+// or the lower Options.ClientRevision. The driver can't speak a revision without client info.
+func clientRevision(opt *Options) (uint64, error) {
+	switch revision := opt.ClientRevision; {
+	case revision == 0 || revision >= ClientTCPProtocolVersion:
+		return ClientTCPProtocolVersion, nil
+	case revision < proto.DBMS_MIN_REVISION_WITH_CLIENT_INFO:
+		return 0, fmt.Errorf("clickhouse: client revision %d is older than the oldest supported one, %d", revision, proto.DBMS_MIN_REVISION_WITH_CLIENT_INFO)
+	default:
+		return revision, nil
+	}
+}
+
 func (c *connect) handshake(database, username, password string) error {
 	defer c.buffer.Reset()
 	c.debugf("[handshake] -> %s", proto.ClientHandshake{})
@@ -32,12 +45,12 @@ func (c *connect) handshake(database, username, password string) error {
 	c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 	defer c.conn.SetReadDeadline(time.Time{})
 	// context level deadlines override any read deadline
-	c.conn.SetDeadline(time.Now().Add(c.opt.DialTimeout))
-	defer c.conn.SetDeadline(time.Time{})
+	c.setDeadline(time.Now().Add(c.opt.DialTimeout))
+	defer c.setDeadline(time.Time{})
 	{
 		c.buffer.PutByte(proto.ClientHello)
 		handshake := &proto.ClientHandshake{
-			ProtocolVersion: ClientTCPProtocolVersion,
+			ProtocolVersion: c.revision,
 			ClientName:      c.opt.ClientInfo.String(),
 			ClientVersion:   proto.Version{ClientVersionMajor, ClientVersionMinor, ClientVersionPatch}, //nolint:govet
 		}
@@ -46,6 +59,7 @@ func (c *connect) handshake(database, username, password string) error {
 			c.buffer.PutString(database)
 			c.buffer.PutString(username)
 			c.buffer.PutString(password)
+			c.secret = [2]int{len(c.buffer.Buf) - len(password), len(c.buffer.Buf)}
 		}
 		if err := c.flush(); err != nil {
 			return err
@@ -58,11 +72,23 @@ func (c *connect) handshake(database, username, password string) error {
 		}
 		switch packet {
 		case proto.ServerException:
-			return c.exception()
+			err := c.exception()
+			if e, ok := err.(*Exception); ok {
+				switch e.Code {
+				case CodeUnknownDatabase:
+					return fmt.Errorf("clickhouse [handshake]: database %q: %w", database, err)
+				case CodeAuthenticationFailed:
+					return fmt.Errorf("clickhouse [handshake]: authentication of user %q failed: %w", username, err)
+				}
+			}
+			return err
 		case proto.ServerHello:
-			if err := c.server.Decode(c.reader); err != nil {
+			if err := c.server.DecodeRevision(c.reader, c.revision); err != nil {
 				return err
 			}
+			if name := c.server.TimezoneName; name != "" && c.server.Timezone.String() != name {
+				c.debugf("[handshake] WARNING: unknown server timezone %q, using UTC", name)
+			}
 		case proto.ServerEndOfStream:
 			c.debugf("[handshake] <- end of stream")
 			return nil
@@ -74,17 +100,23 @@ func (c *connect) handshake(database, username, password string) error {
 		return ErrUnsupportedServerRevision
 	}
 
-	if c.revision > c.server.Revision {
-		c.revision = c.server.Revision
+	if c.revision > c.server.NegotiatedRevision {
+		c.revision = c.server.NegotiatedRevision
 		c.debugf("[handshake] downgrade client proto")
 	}
 	c.debugf("[handshake] <- %s", c.server)
 	return nil
 }
 
+// supports reports whether a feature introduced in the given protocol revision can be used, i.e. both
+// the client and the server know about it. After the handshake c.revision is the lower of their revisions.
+func (c *connect) supports(revision uint64) bool {
+	return c.revision >= revision
+}
+
 func (c *connect) sendAddendum() error {
-	if c.revision >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_QUOTA_KEY {
-		c.buffer.PutString("") // todo quota key support
+	if c.supports(proto.DBMS_MIN_PROTOCOL_VERSION_WITH_QUOTA_KEY) {
+		c.buffer.PutString(c.opt.QuotaKey)
 	}
 
 	return c.flush()
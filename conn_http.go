@@ -32,6 +32,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -41,6 +42,7 @@ import (
 	"github.com/ClickHouse/ch-go/compress"
 	chproto "github.com/ClickHouse/ch-go/proto"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/timezone"
 	"github.com/andybalholm/brotli"
 	"github.com/pkg/errors"
 )
@@ -170,6 +172,9 @@ func dialHttp(ctx context.Context, addr string, num int, opt *Options) (*httpCon
 	if len(opt.Auth.Database) > 0 {
 		query.Set("database", opt.Auth.Database)
 	}
+	if len(opt.QuotaKey) > 0 {
+		query.Set(quotaKeyParamName, opt.QuotaKey)
+	}
 
 	if opt.Compression == nil {
 		opt.Compression = &Compression{
@@ -185,6 +190,8 @@ func dialHttp(ctx context.Context, addr string, num int, opt *Options) (*httpCon
 	for k, v := range opt.Settings {
 		if cv, ok := v.(CustomSetting); ok {
 			v = cv.Value
+		} else {
+			v = settingValue(v)
 		}
 
 		query.Set(k, fmt.Sprint(v))
@@ -199,16 +206,28 @@ func dialHttp(ctx context.Context, addr string, num int, opt *Options) (*httpCon
 			Timeout: opt.DialTimeout,
 		}).DialContext,
 		MaxIdleConns:          1,
-		IdleConnTimeout:       opt.ConnMaxLifetime,
+		IdleConnTimeout:       opt.ConnMaxIdleTime(),
 		ResponseHeaderTimeout: opt.ReadTimeout,
 		TLSClientConfig:       opt.TLS,
 	}
 
+	if opt.Proxy != nil {
+		t.Proxy = http.ProxyURL(opt.Proxy)
+	}
 	if opt.DialContext != nil {
 		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
 			return opt.DialContext(ctx, addr)
 		}
 	}
+	if dialContext := t.DialContext; dialContext != nil {
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialContext(ctx, network, addr)
+			if err != nil {
+				return nil, &dialError{err: err}
+			}
+			return conn, nil
+		}
+	}
 
 	conn := &httpConnect{
 		client: &http.Client{
@@ -223,12 +242,19 @@ func dialHttp(ctx context.Context, addr string, num int, opt *Options) (*httpCon
 		headers:         headers,
 	}
 	location, err := conn.readTimeZone(ctx)
-	if err != nil {
+	switch {
+	case errors.Is(err, errUnknownTimezone):
+		// a timezone the local tzdata doesn't know is no reason to refuse the connection
+		debugf("WARNING: %v, using UTC\n", err)
+	case err != nil:
+		// the keep-alive connection of the failed request isn't closed otherwise
+		t.CloseIdleConnections()
 		return nil, err
 	}
 	if num == 1 {
 		version, err := conn.readVersion(ctx)
 		if err != nil {
+			t.CloseIdleConnections()
 			return nil, err
 		}
 		if !resources.ClientMeta.IsSupportedClickHouseVersion(version) {
@@ -246,8 +272,14 @@ func dialHttp(ctx context.Context, addr string, num int, opt *Options) (*httpCon
 		blockCompressor: compress.NewWriter(),
 		compressionPool: compressionPool,
 		location:        location,
+		userLocation:    opt.Location,
+		timeLayout:      opt.TimeLayout,
 		blockBufferSize: opt.BlockBufferSize,
 		headers:         headers,
+		addr:            addr,
+		metrics:         opt.Metrics,
+		maxStringSize:   opt.MaxStringSize,
+		maxBlockRows:    opt.MaxBlockRows,
 	}, nil
 }
 
@@ -255,20 +287,31 @@ type httpConnect struct {
 	url             *url.URL
 	client          *http.Client
 	location        *time.Location
+	userLocation    *time.Location // Options.Location, DateTime values are decoded in it instead of location when set
+	timeLayout      string
 	buffer          *chproto.Buffer
 	compression     CompressionMethod
 	blockCompressor *compress.Writer
 	compressionPool Pool[HTTPReaderWriter]
 	blockBufferSize uint8
 	headers         map[string]string
+	// defaults are the settings set with Conn.SetSetting when opened with Open
+	defaults *defaultSettings
+
+	addr          string
+	metrics       MetricsHook
+	maxStringSize int
+	maxBlockRows  int
 }
 
 func (h *httpConnect) isBad() bool {
 	return h.client == nil
 }
 
+var errUnknownTimezone = errors.New("unknown server timezone")
+
 func (h *httpConnect) readTimeZone(ctx context.Context) (*time.Location, error) {
-	rows, err := h.query(Context(ctx, ignoreExternalTables()), func(*connect, error) {}, "SELECT timezone()")
+	rows, err := h.query(Context(ctx, internalQuery()), func(*connect, error) {}, "SELECT timezone()")
 	if err != nil {
 		return nil, err
 	}
@@ -282,15 +325,15 @@ func (h *httpConnect) readTimeZone(ctx context.Context) (*time.Location, error)
 		return nil, err
 	}
 
-	location, err := time.LoadLocation(serverLocation)
+	location, err := timezone.Load(serverLocation)
 	if err != nil {
-		return nil, err
+		return time.UTC, fmt.Errorf("%w %q", errUnknownTimezone, serverLocation)
 	}
 	return location, nil
 }
 
 func (h *httpConnect) readVersion(ctx context.Context) (proto.Version, error) {
-	rows, err := h.query(Context(ctx, ignoreExternalTables()), func(*connect, error) {}, "SELECT version()")
+	rows, err := h.query(Context(ctx, internalQuery()), func(*connect, error) {}, "SELECT version()")
 	if err != nil {
 		return proto.Version{}, err
 	}
@@ -375,11 +418,14 @@ func (h *httpConnect) writeData(block *proto.Block) error {
 func (h *httpConnect) readData(ctx context.Context, reader *chproto.Reader) (*proto.Block, error) {
 	opts := queryOptions(ctx)
 	location := h.location
+	if h.userLocation != nil {
+		location = h.userLocation
+	}
 	if opts.userLocation != nil {
 		location = opts.userLocation
 	}
 
-	block := proto.Block{Timezone: location}
+	block := proto.Block{Timezone: location, MaxStringSize: h.maxStringSize, MaxRows: h.maxBlockRows}
 	if h.compression == CompressionLZ4 || h.compression == CompressionZSTD {
 		reader.EnableCompression()
 		defer reader.DisableCompression()
@@ -458,16 +504,27 @@ func (h *httpConnect) createRequest(ctx context.Context, requestUrl string, read
 		if options.quotaKey != "" {
 			query.Set(quotaKeyParamName, options.quotaKey)
 		}
+		settings := make(Settings, len(options.settings))
+		h.defaults.copyTo(settings)
 		for key, value := range options.settings {
+			settings[key] = value
+		}
+		for key, value := range settings {
 			// check that query doesn't change format
 			if key == "default_format" {
 				continue
 			}
 			if cv, ok := value.(CustomSetting); ok {
 				value = cv.Value
+			} else {
+				value = settingValue(value)
 			}
 			query.Set(key, fmt.Sprint(value))
 		}
+		// the Options.Settings are already in the query of requestUrl
+		if options.maxExecutionTime > 0 && !query.Has("max_execution_time") {
+			query.Set("max_execution_time", strconv.Itoa(options.maxExecutionTime))
+		}
 		for key, value := range options.parameters {
 			query.Set(fmt.Sprintf("param_%s", key), value)
 		}
@@ -536,13 +593,23 @@ func (h *httpConnect) executeRequest(req *http.Request) (*http.Response, error)
 		if err != nil {
 			return nil, fmt.Errorf("clickhouse [execute]:: %d code: failed to read the response: %w", resp.StatusCode, err)
 		}
-		return nil, fmt.Errorf("clickhouse [execute]:: %d code: %s", resp.StatusCode, string(msg))
+		return nil, &httpStatusError{statusCode: resp.StatusCode, message: string(msg)}
 	}
 	return resp, nil
 }
 
+// httpStatusError is a response of the server with a status other than 200 OK.
+type httpStatusError struct {
+	statusCode int
+	message    string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("clickhouse [execute]:: %d code: %s", e.statusCode, e.message)
+}
+
 func (h *httpConnect) ping(ctx context.Context) error {
-	rows, err := h.query(Context(ctx, ignoreExternalTables()), nil, "SELECT 1")
+	rows, err := h.query(Context(ctx, internalQuery()), nil, "SELECT 1")
 	if err != nil {
 		return err
 	}
@@ -49,7 +49,9 @@ func (h *httpConnect) prepareBatch(ctx context.Context, query string, opts drive
 			rColumns[i] = strings.Trim(strings.TrimSpace(rColumns[i]), "`")
 		}
 	}
-	query = "INSERT INTO " + tableName + " FORMAT Native"
+	// keep any SETTINGS clause, it applies to the insert itself e.g. async_insert
+	settings := insertSettingsRe.FindString(splitInsertRe.Split(query, -1)[0])
+	query = "INSERT INTO " + tableName + settings + " FORMAT Native"
 	queryTableSchema := "DESCRIBE TABLE " + tableName
 	r, err := h.query(ctx, release, queryTableSchema)
 	if err != nil {
@@ -117,6 +119,15 @@ type httpBatch struct {
 	structMap *structMap
 	sent      bool
 	block     *proto.Block
+	// release hands the connection back to the pool of Open once the batch is done, nil for std
+	release func(error)
+}
+
+func (b *httpBatch) done(err error) {
+	if b.release != nil {
+		b.release(err)
+		b.release = nil
+	}
 }
 
 // Flush TODO: noop on http currently - requires streaming to be implemented
@@ -127,6 +138,7 @@ func (b *httpBatch) Flush() error {
 func (b *httpBatch) Abort() error {
 	defer func() {
 		b.sent = true
+		b.done(nil)
 	}()
 	if b.sent {
 		return ErrBatchAlreadySent
@@ -177,6 +189,7 @@ func (b *httpBatch) IsSent() bool {
 func (b *httpBatch) Send() (err error) {
 	defer func() {
 		b.sent = true
+		b.done(err)
 	}()
 	if b.sent {
 		return ErrBatchAlreadySent
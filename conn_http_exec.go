@@ -29,12 +29,15 @@ func (h *httpConnect) exec(ctx context.Context, query string, args ...any) error
 		return err
 	}
 
+	metrics := h.startQueryMetrics(ctx, query, options.queryID)
 	res, err := h.sendQuery(ctx, query, &options, h.headers)
 	if res != nil {
 		defer res.Body.Close()
+		metrics.response(res)
 		// we don't care about result, so just discard it to reuse connection
 		_, _ = io.Copy(io.Discard, res.Body)
 	}
 
+	metrics.end(err)
 	return err
 }
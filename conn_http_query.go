@@ -46,12 +46,19 @@ func (h *httpConnect) query(ctx context.Context, release func(*connect, error),
 		headers[k] = v
 	}
 
+	var metrics *queryMetrics
+	if !options.internal {
+		metrics = h.startQueryMetrics(ctx, query, options.queryID)
+	}
 	res, err := h.sendQuery(ctx, query, &options, headers)
 	if err != nil {
+		metrics.end(err)
 		return nil, err
 	}
+	metrics.response(res)
 
 	if res.ContentLength == 0 {
+		metrics.end(nil)
 		block := &proto.Block{}
 		return &rows{
 			block:     block,
@@ -68,6 +75,7 @@ func (h *httpConnect) query(ctx context.Context, release func(*connect, error),
 	// automatically as they might not have permissions.
 	reader, err := rw.NewReader(res)
 	if err != nil {
+		metrics.end(err)
 		res.Body.Close()
 		h.compressionPool.Put(rw)
 		return nil, err
@@ -75,10 +83,14 @@ func (h *httpConnect) query(ctx context.Context, release func(*connect, error),
 	chReader := chproto.NewReader(reader)
 	block, err := h.readData(ctx, chReader)
 	if err != nil && !errors.Is(err, io.EOF) {
+		metrics.end(err)
 		res.Body.Close()
 		h.compressionPool.Put(rw)
 		return nil, err
 	}
+	if block != nil {
+		metrics.block(block)
+	}
 
 	bufferSize := h.blockBufferSize
 	if options.blockBufferSize > 0 {
@@ -86,26 +98,33 @@ func (h *httpConnect) query(ctx context.Context, release func(*connect, error),
 		bufferSize = options.blockBufferSize
 	}
 	var (
-		errCh  = make(chan error)
+		// buffered like the native one, the error is queued before the stream is closed
+		errCh  = make(chan error, 1)
 		stream = make(chan *proto.Block, bufferSize)
 	)
 	go func() {
+		var queryErr error
+	read:
 		for {
 			block, err := h.readData(ctx, chReader)
 			if err != nil {
 				// ch-go wraps EOF errors
 				if !errors.Is(err, io.EOF) {
+					queryErr = err
 					errCh <- err
 				}
 				break
 			}
+			metrics.block(block)
 			select {
 			case <-ctx.Done():
+				queryErr = ctx.Err()
 				errCh <- ctx.Err()
-				break
+				break read
 			case stream <- block:
 			}
 		}
+		metrics.end(queryErr)
 		res.Body.Close()
 		h.compressionPool.Put(rw)
 		close(stream)
@@ -116,10 +135,11 @@ func (h *httpConnect) query(ctx context.Context, release func(*connect, error),
 		block = &proto.Block{}
 	}
 	return &rows{
-		block:     block,
-		stream:    stream,
-		errors:    errCh,
-		columns:   block.ColumnsNames(),
-		structMap: &structMap{},
+		block:      block,
+		stream:     stream,
+		errors:     errCh,
+		columns:    block.ColumnsNames(),
+		structMap:  &structMap{},
+		timeLayout: h.timeLayout,
 	}, nil
 }
@@ -0,0 +1,96 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package clickhouse
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keepAlive reports whether keep-alive is enabled on conn and its idle time in seconds.
+func keepAlive(t *testing.T, conn net.Conn) (enabled bool, idle int) {
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	require.NoError(t, err)
+	require.NoError(t, raw.Control(func(fd uintptr) {
+		on, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		require.NoError(t, err)
+		enabled = on != 0
+		idle, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		require.NoError(t, err)
+	}))
+	return enabled, idle
+}
+
+func TestTCPKeepAlive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go newFakeServer().serve(conn)
+		}
+	}()
+	addr := listener.Addr().String()
+
+	testCases := []struct {
+		name      string
+		keepAlive time.Duration
+		custom    bool
+		enabled   bool
+		idle      int
+	}{
+		{"default", 0, false, true, 15},
+		{"configured", 42 * time.Second, false, true, 42},
+		{"disabled", -1, false, false, 0},
+		{"custom dialer", 42 * time.Second, true, true, 42},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := &Options{Addr: []string{addr}, TCPKeepAlive: tc.keepAlive}
+			if tc.custom {
+				opt.DialContext = func(ctx context.Context, addr string) (net.Conn, error) {
+					var d net.Dialer
+					d.KeepAlive = -1
+					return d.DialContext(ctx, "tcp", addr)
+				}
+			}
+			c, err := dial(context.Background(), addr, 1, opt.setDefaults())
+			require.NoError(t, err)
+			defer c.close()
+
+			enabled, idle := keepAlive(t, c.conn)
+			assert.Equal(t, tc.enabled, enabled)
+			if tc.enabled {
+				assert.Equal(t, tc.idle, idle)
+			}
+		})
+	}
+}
@@ -19,7 +19,6 @@ package clickhouse
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
@@ -28,13 +27,17 @@ import (
 // Connection::ping
 // https://github.com/ClickHouse/ClickHouse/blob/master/src/Client/Connection.cpp
 func (c *connect) ping(ctx context.Context) (err error) {
+	if err := c.begin("ping"); err != nil {
+		return err
+	}
+	defer c.end()
 	// set a read deadline - alternative to context.Read operation will fail if no data is received after deadline.
 	c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 	defer c.conn.SetReadDeadline(time.Time{})
 	// context level deadlines override any read deadline
 	if deadline, ok := ctx.Deadline(); ok {
-		c.conn.SetDeadline(deadline)
-		defer c.conn.SetDeadline(time.Time{})
+		c.setDeadline(deadline)
+		defer c.setDeadline(time.Time{})
 	}
 	c.debugf("[ping] -> ping")
 	c.buffer.PutByte(proto.ClientPing)
@@ -42,23 +45,25 @@ func (c *connect) ping(ctx context.Context) (err error) {
 		return err
 	}
 
-	var packet byte
+	var (
+		packet  byte
+		options = queryOptions(ctx)
+		// a busy server may interleave control packets ahead of the pong, they are
+		// consumed like in the query path and passed to the context callbacks
+		on = options.onProcess()
+	)
 	for {
 		if packet, err = c.reader.ReadByte(); err != nil {
 			return err
 		}
 		switch packet {
-		case proto.ServerException:
-			return c.exception()
-		case proto.ServerProgress:
-			if _, err = c.progress(); err != nil {
-				return err
-			}
 		case proto.ServerPong:
 			c.debugf("[ping] <- pong")
 			return nil
 		default:
-			return fmt.Errorf("unexpected packet %d", packet)
+			if err := c.handle(ctx, packet, on); err != nil {
+				return err
+			}
 		}
 	}
 }
@@ -0,0 +1,88 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingControlPackets(t *testing.T) {
+	t.Run("consumed before pong", func(t *testing.T) {
+		var (
+			progress int
+			srv      = newFakeServer().progress(1, 0).profileInfo(1).progress(2, 0).pong()
+			ctx      = Context(context.Background(), WithProgress(func(*Progress) { progress++ }))
+		)
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.Ping(ctx))
+		assert.Equal(t, 2, progress)
+	})
+
+	t.Run("exception", func(t *testing.T) {
+		srv := newFakeServer().profileInfo(1).raise(159, "Timeout exceeded")
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		err = conn.Ping(context.Background())
+		var exception *Exception
+		require.ErrorAs(t, err, &exception)
+		assert.EqualValues(t, 159, exception.Code)
+	})
+
+	t.Run("unknown packet", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.script.PutByte(0xff)
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		assert.ErrorContains(t, conn.Ping(context.Background()), "unexpected packet 255")
+	})
+}
+
+func TestPingBusyConnection(t *testing.T) {
+	srv := newFakeServer().blocks(t, 2, 10).pong()
+	c, err := dial(context.Background(), "fake", 1, (&Options{DialContext: srv.dial}).setDefaults())
+	require.NoError(t, err)
+	defer c.close()
+
+	rows, err := c.query(context.Background(), func(*connect, error) {}, "SELECT id, name, code FROM t")
+	require.NoError(t, err)
+	err = c.ping(context.Background())
+	require.ErrorIs(t, err, ErrConnBusy)
+	assert.EqualError(t, err, "ping: "+ErrConnBusy.Error())
+	_, err = c.query(context.Background(), func(*connect, error) {}, "SELECT 1")
+	require.ErrorIs(t, err, ErrConnBusy)
+
+	// the rows are read whole, the second query didn't consume any of their packets
+	var count int
+	for rows.Next() {
+		count++
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, 20, count)
+	assert.NoError(t, c.ping(context.Background()), "the connection is idle once the rows are read")
+}
@@ -19,11 +19,16 @@ package clickhouse
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 	"io"
 )
 
+// errEndOfStream is returned by firstBlock when the response ends without a block, e.g. for a
+// statement with no result set. It wraps io.EOF for the callers that expect a block.
+var errEndOfStream = fmt.Errorf("end of stream: %w", io.EOF)
+
 type onProcess struct {
 	data          func(*proto.Block)
 	logs          func([]Log)
@@ -42,16 +47,21 @@ func (c *connect) firstBlock(ctx context.Context, on *onProcess) (*proto.Block,
 		}
 		packet, err := c.reader.ReadByte()
 		if err != nil {
+			c.discard(ctx, err)
 			return nil, err
 		}
+		c.responded = true
 		switch packet {
 		case proto.ServerData:
-			return c.readData(ctx, packet, true)
+			block, err := c.readData(ctx, packet, true)
+			c.discard(ctx, err)
+			return block, err
 		case proto.ServerEndOfStream:
 			c.debugf("[end of stream]")
-			return nil, io.EOF
+			return nil, errEndOfStream
 		default:
 			if err := c.handle(ctx, packet, on); err != nil {
+				c.discard(ctx, err)
 				return nil, err
 			}
 		}
@@ -68,6 +78,7 @@ func (c *connect) process(ctx context.Context, on *onProcess) error {
 		}
 		packet, err := c.reader.ReadByte()
 		if err != nil {
+			c.discard(ctx, err)
 			return err
 		}
 		switch packet {
@@ -76,6 +87,7 @@ func (c *connect) process(ctx context.Context, on *onProcess) error {
 			return nil
 		}
 		if err := c.handle(ctx, packet, on); err != nil {
+			c.discard(ctx, err)
 			return err
 		}
 	}
@@ -134,6 +146,22 @@ func (c *connect) handle(ctx context.Context, packet byte, on *onProcess) error
 	return nil
 }
 
+// discard closes the connection when reading a response stops on err at an unknown point of it,
+// so it's never reused, not even by database/sql which keeps its connections past an error. An
+// exception is the last packet of a response, the connection is ready for the next query then.
+func (c *connect) discard(ctx context.Context, err error) {
+	var exception *Exception
+	switch {
+	case err == nil, c.closed, errors.As(err, &exception):
+	case ctx.Err() != nil:
+		// the read was interrupted as ctx is done, the server is told to stop the query as well
+		c.cancel()
+	default:
+		c.debugf("[discard] %v", err)
+		c.close()
+	}
+}
+
 func (c *connect) cancel() error {
 	c.debugf("[cancel]")
 	c.buffer.PutUVarInt(proto.ClientCancel)
@@ -25,15 +25,20 @@ import (
 )
 
 func (c *connect) query(ctx context.Context, release func(*connect, error), query string, args ...any) (*rows, error) {
+	// the connection belongs to the request that made it busy, so it isn't released here
+	if err := c.begin("query"); err != nil {
+		return nil, err
+	}
 	var (
 		options                    = queryOptions(ctx)
 		onProcess                  = options.onProcess()
-		queryParamsProtocolSupport = c.revision >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_PARAMETERS
+		queryParamsProtocolSupport = c.supports(proto.DBMS_MIN_PROTOCOL_VERSION_WITH_PARAMETERS)
 		body, err                  = bindQueryOrAppendParameters(queryParamsProtocolSupport, &options, query, c.server.Timezone, args...)
 	)
 
 	if err != nil {
 		c.debugf("[bindQuery] error: %v", err)
+		c.end()
 		release(c, err)
 		return nil, err
 	}
@@ -43,22 +48,40 @@ func (c *connect) query(ctx context.Context, release func(*connect, error), quer
 	defer c.conn.SetReadDeadline(time.Time{})
 	// context level deadlines override any read deadline
 	if deadline, ok := ctx.Deadline(); ok {
-		c.conn.SetDeadline(deadline)
-		defer c.conn.SetDeadline(time.Time{})
+		c.setDeadline(deadline)
+		defer c.setDeadline(time.Time{})
 	}
 
+	metrics := c.startQueryMetrics(ctx, body, options.queryID)
+	metrics.observe(onProcess)
+	c.responded = false
 	if err = c.sendQuery(body, &options); err != nil {
+		metrics.end(err)
+		c.end()
 		release(c, err)
 		return nil, err
 	}
 
 	init, err := c.firstBlock(ctx, onProcess)
-
+	if err == errEndOfStream {
+		// a statement without a result set, e.g. an ALTER, has no rows rather than failing
+		metrics.end(nil)
+		c.end()
+		release(c, nil)
+		return &rows{
+			block:      &proto.Block{},
+			structMap:  c.structMap,
+			timeLayout: c.opt.TimeLayout,
+		}, nil
+	}
 	if err != nil {
 		c.debugf("[query] first block error: %v", err)
+		metrics.end(err)
+		c.end()
 		release(c, err)
 		return nil, err
 	}
+	metrics.block(init)
 	bufferSize := c.blockBufferSize
 	if options.blockBufferSize > 0 {
 		// allow block buffer sze to be overridden per query
@@ -71,6 +94,7 @@ func (c *connect) query(ctx context.Context, release func(*connect, error), quer
 
 	go func() {
 		onProcess.data = func(b *proto.Block) {
+			metrics.block(b)
 			stream <- b
 		}
 		err := c.process(ctx, onProcess)
@@ -78,28 +102,22 @@ func (c *connect) query(ctx context.Context, release func(*connect, error), quer
 			c.debugf("[query] process error: %v", err)
 			errors <- err
 		}
+		metrics.end(err)
+		c.end()
 		close(stream)
 		close(errors)
 		release(c, err)
 	}()
 
 	return &rows{
-		block:     init,
-		stream:    stream,
-		errors:    errors,
-		columns:   init.ColumnsNames(),
-		structMap: c.structMap,
+		block:      init,
+		stream:     stream,
+		errors:     errors,
+		columns:    init.ColumnsNames(),
+		structMap:  c.structMap,
+		timeLayout: c.opt.TimeLayout,
+		recycle: func(b *proto.Block) {
+			c.blocks.Put(b)
+		},
 	}, nil
 }
-
-func (c *connect) queryRow(ctx context.Context, release func(*connect, error), query string, args ...any) *row {
-	rows, err := c.query(ctx, release, query, args...)
-	if err != nil {
-		return &row{
-			err: err,
-		}
-	}
-	return &row{
-		rows: rows,
-	}
-}
@@ -0,0 +1,737 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/ch-go/compress"
+	chproto "github.com/ClickHouse/ch-go/proto"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blocks scripts a query result of n blocks with rows rows each.
+func (s *fakeServer) blocks(tb testing.TB, n, rows int) *fakeServer {
+	s.data(&proto.Block{}) // the header only first block
+	for i := 0; i < n; i++ {
+		block := &proto.Block{}
+		require.NoError(tb, block.AddColumn("id", "UInt64"))
+		require.NoError(tb, block.AddColumn("name", "String"))
+		require.NoError(tb, block.AddColumn("code", "FixedString(4)"))
+		for j := 0; j < rows; j++ {
+			id := uint64(i*rows + j)
+			require.NoError(tb, block.Append(id, fmt.Sprintf("row %d", id), fmt.Sprintf("%04d", id%10000)))
+		}
+		s.data(block)
+	}
+	return s.endOfStream()
+}
+
+func TestQueryReusesBlocks(t *testing.T) {
+	const numBlocks, numRows = 20, 100
+	srv := newFakeServer().blocks(t, numBlocks, numRows)
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+	require.NoError(t, err)
+
+	var (
+		names []string
+		codes [][]byte
+	)
+	for i := uint64(0); rows.Next(); i++ {
+		var (
+			id   uint64
+			name string
+			code []byte
+		)
+		require.NoError(t, rows.Scan(&id, &name, &code))
+		require.Equal(t, i, id)
+		names, codes = append(names, name), append(codes, code)
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, names, numBlocks*numRows)
+	// values scanned from earlier blocks must not change when their buffers are reused
+	for i := range names {
+		assert.Equal(t, fmt.Sprintf("row %d", i), names[i])
+		assert.Equal(t, fmt.Sprintf("%04d", i), string(codes[i]))
+	}
+}
+
+func BenchmarkQueryBlocks(b *testing.B) {
+	srv := newFakeServer().blocks(b, 100, 1000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(b, err)
+		rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+		require.NoError(b, err)
+		for rows.Next() {
+		}
+		require.NoError(b, rows.Err())
+		conn.Close()
+	}
+}
+
+func TestQueryControlPackets(t *testing.T) {
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("id", "UInt64"))
+	require.NoError(t, block.Append(uint64(1)))
+
+	srv := newFakeServer().progress(1, 0).data(&proto.Block{}).profileInfo(1).progress(1, 0).data(block).progress(0, 0).endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var id uint64
+	require.NoError(t, conn.QueryRow(context.Background(), "SELECT 1").Scan(&id))
+	assert.EqualValues(t, 1, id)
+}
+
+func TestQueryNull(t *testing.T) {
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("NULL", "Nullable(Nothing)"))
+	// a single NULL: the null mask followed by one placeholder byte of Nothing
+	require.NoError(t, block.Columns[0].Decode(chproto.NewReader(bytes.NewReader([]byte{1, 0})), 1))
+	srv := newFakeServer().data(block).endOfStream()
+
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT NULL")
+	require.NoError(t, err)
+	assert.Equal(t, "Nullable(Nothing)", rows.ColumnTypes()[0].DatabaseTypeName())
+
+	var values []any
+	for rows.Next() {
+		value := any("not null")
+		require.NoError(t, rows.Scan(&value))
+		values = append(values, value)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []any{nil}, values)
+}
+
+func TestQueryGeo(t *testing.T) {
+	var (
+		point   = [2]float64{1.5, -2}
+		polygon = [][][2]float64{{{0, 0}, {1, 0}, {0, 1}, {0, 0}}}
+		block   = &proto.Block{}
+	)
+	require.NoError(t, block.AddColumn("p", "Point"))
+	require.NoError(t, block.AddColumn("poly", "Polygon"))
+	require.NoError(t, block.Append(point, polygon))
+	srv := newFakeServer().data(block).endOfStream()
+
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var (
+		p    [2]float64
+		poly [][][2]float64
+	)
+	require.NoError(t, conn.QueryRow(context.Background(), "SELECT p, poly FROM t").Scan(&p, &poly))
+	assert.Equal(t, point, p)
+	assert.Equal(t, polygon, poly)
+}
+
+func TestQueryRetryReads(t *testing.T) {
+	// servers are handed out in order, one per dialed connection
+	dialer := func(servers ...*fakeServer) (func(context.Context, string) (net.Conn, error), *atomic.Int64) {
+		var dials atomic.Int64
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			n := int(dials.Add(1)) - 1
+			return servers[min(n, len(servers)-1)].dial(ctx, addr)
+		}, &dials
+	}
+	broken := func() *fakeServer {
+		srv := newFakeServer()
+		srv.hangup = true
+		return srv
+	}
+
+	t.Run("fail before first row", func(t *testing.T) {
+		for _, retries := range []int{0, 1} {
+			dial, dials := dialer(broken(), newFakeServer().blocks(t, 1, 5))
+			conn, err := Open(&Options{DialContext: dial, RetryReads: retries})
+			require.NoError(t, err)
+
+			var id uint64
+			err = conn.QueryRow(context.Background(), "SELECT id, name, code FROM t").Scan(&id, new(string), new(string))
+			if retries == 0 {
+				assert.ErrorIs(t, err, io.EOF)
+				assert.EqualValues(t, 1, dials.Load())
+			} else {
+				require.NoError(t, err)
+				assert.EqualValues(t, 2, dials.Load())
+			}
+			conn.Close()
+		}
+	})
+
+	t.Run("fail mid-iteration", func(t *testing.T) {
+		srv := broken().blocks(t, 1, 5)
+		// drop the end of stream so the connection breaks after the first block
+		srv.script.Buf = srv.script.Buf[:len(srv.script.Buf)-1]
+		dial, dials := dialer(srv, newFakeServer().blocks(t, 1, 5))
+		conn, err := Open(&Options{DialContext: dial, RetryReads: 3})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+		require.NoError(t, err)
+		var n int
+		for rows.Next() {
+			n++
+		}
+		assert.Equal(t, 5, n)
+		assert.ErrorIs(t, rows.Err(), io.EOF)
+		assert.EqualValues(t, 1, dials.Load())
+	})
+
+	t.Run("statement without result set", func(t *testing.T) {
+		srv := newFakeServer().endOfStream()
+		dial, dials := dialer(srv)
+		conn, err := Open(&Options{DialContext: dial, RetryReads: 3})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rows, err := conn.Query(context.Background(), "ALTER TABLE t DELETE WHERE 1")
+		require.NoError(t, err)
+		assert.False(t, rows.Next())
+		assert.NoError(t, rows.Err())
+		assert.EqualValues(t, 1, dials.Load())
+		assert.Equal(t, 1, bytes.Count(srv.sent(), []byte("ALTER TABLE t DELETE WHERE 1")))
+	})
+
+	t.Run("not retried", func(t *testing.T) {
+		for name, c := range map[string]struct {
+			srv   *fakeServer
+			query string
+		}{
+			"not a select":              {broken(), "ALTER TABLE t DELETE WHERE 1"},
+			"after the server answered": {broken().progress(1, 1), "SELECT id, name, code FROM t"},
+		} {
+			dial, dials := dialer(c.srv, newFakeServer().blocks(t, 1, 5))
+			conn, err := Open(&Options{DialContext: dial, RetryReads: 3})
+			require.NoError(t, err)
+
+			_, err = conn.Query(context.Background(), c.query)
+			assert.ErrorIs(t, err, io.EOF, name)
+			assert.EqualValues(t, 1, dials.Load(), name)
+			conn.Close()
+		}
+	})
+}
+
+func TestQueryBool(t *testing.T) {
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("flag", "Bool"))
+	require.NoError(t, block.AddColumn("raw", "UInt8"))
+	require.NoError(t, block.Append(true, uint8(1)))
+	require.NoError(t, block.Append(false, uint8(0)))
+	srv := newFakeServer().data(block).endOfStream()
+
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT flag, raw FROM t")
+	require.NoError(t, err)
+	types := rows.ColumnTypes()
+	assert.Equal(t, "Bool", types[0].DatabaseTypeName())
+	assert.Equal(t, reflect.TypeOf(true), types[0].ScanType())
+	assert.Equal(t, reflect.TypeOf(uint8(0)), types[1].ScanType())
+
+	var flags []bool
+	for rows.Next() {
+		var flag, raw bool
+		require.NoError(t, rows.Scan(&flag, &raw))
+		assert.Equal(t, flag, raw)
+		flags = append(flags, flag)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []bool{true, false}, flags)
+}
+
+func TestQueryCompression(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method CompressionMethod
+	}{
+		{"lz4", CompressionLZ4},
+		{"zstd", CompressionZSTD},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			block := &proto.Block{}
+			require.NoError(t, block.AddColumn("id", "UInt64"))
+			for i := 0; i < 100; i++ {
+				require.NoError(t, block.Append(uint64(i)))
+			}
+			srv := newFakeServer().compressedData(block, compress.Method(tc.method)).endOfStream()
+
+			conn, err := Open(&Options{DialContext: srv.dial, Compression: &Compression{Method: tc.method}})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			const query = "SELECT id FROM t"
+			rows, err := conn.Query(context.Background(), query)
+			require.NoError(t, err)
+			var sum uint64
+			for rows.Next() {
+				var id uint64
+				require.NoError(t, rows.Scan(&id))
+				sum += id
+			}
+			require.NoError(t, rows.Err())
+			assert.EqualValues(t, 4950, sum)
+
+			// the query packet asks the server to compress: the complete stage followed by the compression flag
+			flag := append([]byte{proto.StateComplete, 1, byte(len(query))}, query...)
+			assert.True(t, bytes.Contains(srv.sent(), flag))
+		})
+	}
+}
+
+// readCounter counts the reads made from the underlying connection, i.e. the syscalls on a real socket.
+type readCounter struct {
+	net.Conn
+	reads *atomic.Int64
+}
+
+func (r readCounter) Read(p []byte) (int, error) {
+	r.reads.Add(1)
+	return r.Conn.Read(p)
+}
+
+// byteCounter counts the bytes read from the underlying connection.
+type byteCounter struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (r byteCounter) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	r.read.Add(int64(n))
+	return n, err
+}
+
+// BenchmarkQueryLargeBlock selects a block of a few MB with the default and a larger read buffer.
+func BenchmarkQueryLargeBlock(b *testing.B) {
+	srv := newFakeServer().blocks(b, 1, 200_000)
+	for _, size := range []int{0, 1 << 20} {
+		b.Run(fmt.Sprintf("read_buffer_size=%d", size), func(b *testing.B) {
+			var (
+				reads atomic.Int64
+				dial  = func(ctx context.Context, addr string) (net.Conn, error) {
+					conn, err := srv.dial(ctx, addr)
+					return readCounter{Conn: conn, reads: &reads}, err
+				}
+			)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				conn, err := Open(&Options{DialContext: dial, ReadBufferSize: size})
+				require.NoError(b, err)
+				rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+				require.NoError(b, err)
+				for rows.Next() {
+				}
+				require.NoError(b, rows.Err())
+				require.NoError(b, conn.Close())
+			}
+			b.ReportMetric(float64(reads.Load())/float64(b.N), "reads/op")
+		})
+	}
+}
+
+func TestQueryReadBufferSize(t *testing.T) {
+	const numRows = 50_000
+	for _, size := range []int{1 << 20, 4096} {
+		t.Run(fmt.Sprintf("read_buffer_size=%d", size), func(t *testing.T) {
+			var (
+				longest atomic.Int64
+				srv     = newFakeServer().blocks(t, 1, numRows)
+				dial    = func(ctx context.Context, addr string) (net.Conn, error) {
+					conn, err := srv.dial(ctx, addr)
+					return readLength{Conn: conn, longest: &longest}, err
+				}
+			)
+			conn, err := Open(&Options{DialContext: dial, ReadBufferSize: size})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+			require.NoError(t, err)
+			var count int
+			for rows.Next() {
+				var (
+					id   uint64
+					name string
+					code string
+				)
+				require.NoError(t, rows.Scan(&id, &name, &code))
+				assert.Equal(t, fmt.Sprintf("row %d", id), name)
+				count++
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, numRows, count)
+			assert.LessOrEqual(t, longest.Load(), int64(size), "the connection is read into at most the buffer size at a time")
+		})
+	}
+}
+
+// readLength keeps the longest read of the underlying connection.
+type readLength struct {
+	net.Conn
+	longest *atomic.Int64
+}
+
+func (r readLength) Read(p []byte) (int, error) {
+	if n := int64(len(p)); n > r.longest.Load() {
+		r.longest.Store(n)
+	}
+	return r.Conn.Read(p)
+}
+
+func TestQueryTimeString(t *testing.T) {
+	value := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	query := func(t *testing.T, layout string) (text string, ptr, null *string, t0 time.Time) {
+		block := &proto.Block{}
+		require.NoError(t, block.AddColumn("t", "DateTime"))
+		require.NoError(t, block.AddColumn("n", "Nullable(DateTime)"))
+		require.NoError(t, block.AddColumn("z", "Nullable(DateTime)"))
+		require.NoError(t, block.Append(value, &value, nil))
+
+		// both queries run on the same connection, which the script answers in turn
+		srv := newFakeServer().data(block).endOfStream().data(block).endOfStream()
+		conn, err := Open(&Options{DialContext: srv.dial, TimeLayout: layout})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		null = new(string)
+		require.NoError(t, conn.QueryRow(context.Background(), "SELECT t, n, z").Scan(&text, &ptr, &null))
+		var n, z *time.Time
+		require.NoError(t, conn.QueryRow(context.Background(), "SELECT t, n, z").Scan(&t0, &n, &z))
+		return text, ptr, null, t0
+	}
+
+	t.Run("RFC3339 by default", func(t *testing.T) {
+		text, ptr, null, t0 := query(t, "")
+		assert.Equal(t, "2024-03-01T12:30:00Z", text)
+		require.NotNil(t, ptr)
+		assert.Equal(t, "2024-03-01T12:30:00Z", *ptr)
+		assert.Nil(t, null)
+		assert.True(t, value.Equal(t0))
+	})
+	t.Run("custom layout", func(t *testing.T) {
+		text, ptr, _, _ := query(t, time.DateTime)
+		assert.Equal(t, "2024-03-01 12:30:00", text)
+		require.NotNil(t, ptr)
+		assert.Equal(t, "2024-03-01 12:30:00", *ptr)
+	})
+}
+
+func TestQueryStreamsBlocks(t *testing.T) {
+	const numBlocks, numRows = 20, 20_000
+	var (
+		read atomic.Int64
+		srv  = newFakeServer().blocks(t, numBlocks, numRows)
+		dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			conn, err := srv.dial(ctx, addr)
+			return byteCounter{Conn: conn, read: &read}, err
+		}
+	)
+	conn, err := Open(&Options{DialContext: dial, Settings: Settings{"max_block_size": numRows}, BlockBufferSize: 1})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	// let the reader fill the block buffer before measuring
+	time.Sleep(50 * time.Millisecond)
+	total := len(srv.script.Buf)
+	assert.Less(t, read.Load(), int64(total/4), "the result isn't read ahead of the rows scanned")
+
+	count := 1
+	for rows.Next() {
+		count++
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, numBlocks*numRows, count)
+	assert.Contains(t, string(srv.sent()), "max_block_size")
+}
+
+func TestQueryReadsBlocksLazily(t *testing.T) {
+	const numBlocks, numRows = 10, 1000
+	var (
+		decoded atomic.Int64
+		srv     = newFakeServer().blocks(t, numBlocks, numRows)
+	)
+	conn, err := Open(&Options{
+		DialContext:     srv.dial,
+		BlockBufferSize: 1,
+		Debug:           true,
+		Debugf: func(format string, v ...any) {
+			if strings.HasPrefix(format, "[read data] compression=") {
+				decoded.Add(1)
+			}
+		},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+	require.NoError(t, err)
+	var count int
+	for rows.Next() {
+		count++
+		// the header block, the one being scanned, the one in the buffer and the one waiting to be buffered
+		consumed := int64((count-1)/numRows + 1)
+		assert.LessOrEqual(t, decoded.Load(), 1+consumed+2, "row %d", count)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, numBlocks*numRows, count)
+	assert.EqualValues(t, 1+numBlocks, decoded.Load())
+}
+
+func TestQueryCloseEarly(t *testing.T) {
+	result := &proto.Block{}
+	require.NoError(t, result.AddColumn("x", "UInt8"))
+	require.NoError(t, result.Append(uint8(42)))
+
+	t.Run("drained", func(t *testing.T) {
+		var (
+			dials atomic.Int64
+			srv   = newFakeServer().blocks(t, 5, 100).data(result).endOfStream()
+			dial  = func(ctx context.Context, addr string) (net.Conn, error) {
+				dials.Add(1)
+				return srv.dial(ctx, addr)
+			}
+		)
+		conn, err := Open(&Options{DialContext: dial, MaxOpenConns: 1})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+		require.NoError(t, err)
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Close())
+
+		var x uint8
+		require.NoError(t, conn.QueryRow(context.Background(), "SELECT 42").Scan(&x))
+		assert.EqualValues(t, 42, x)
+		assert.EqualValues(t, 1, dials.Load(), "the connection is reused")
+	})
+
+	t.Run("broken while draining", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.data(&proto.Block{}).data(result).data(result) // no end of stream
+		srv.hangup = true
+		conn, err := Open(&Options{DialContext: srv.dial, MaxOpenConns: 1})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rows, err := conn.Query(context.Background(), "SELECT x FROM t")
+		require.NoError(t, err)
+		require.True(t, rows.Next())
+		assert.Error(t, rows.Close())
+		assert.Equal(t, 0, conn.Stats().Idle, "the broken connection isn't put back")
+	})
+}
+
+func TestQueryBlocks(t *testing.T) {
+	ids := func(block driver.Block) []uint64 {
+		var ids []uint64
+		for i := 0; i < block.Columns[0].Rows(); i++ {
+			ids = append(ids, block.Columns[0].Row(i, false).(uint64))
+		}
+		return ids
+	}
+
+	t.Run("in order", func(t *testing.T) {
+		srv := newFakeServer().blocks(t, 2, 3)
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		blocks, err := conn.(driver.BlocksConn).QueryBlocks(context.Background(), "SELECT id, name, code FROM t")
+		require.NoError(t, err)
+		var got [][]uint64
+		for block := range blocks {
+			require.NoError(t, block.Err)
+			if len(block.Columns) != 0 && block.Columns[0].Rows() != 0 {
+				got = append(got, ids(block))
+				assert.Equal(t, "name", block.Columns[1].Name())
+				assert.Equal(t, column.Type("FixedString(4)"), block.Columns[2].Type())
+			}
+		}
+		assert.Equal(t, [][]uint64{{0, 1, 2}, {3, 4, 5}}, got, "the channel is closed after the last block")
+		assert.Eventually(t, func() bool { return conn.Stats().Idle == 1 }, time.Second, 10*time.Millisecond,
+			"the connection is released once the result is read")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		block := &proto.Block{}
+		require.NoError(t, block.AddColumn("id", "UInt64"))
+		require.NoError(t, block.Append(uint64(1)))
+		srv := newFakeServer().data(block).data(block).raise(241, "Memory limit exceeded")
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		blocks, err := conn.(driver.BlocksConn).QueryBlocks(context.Background(), "SELECT id FROM t")
+		require.NoError(t, err)
+		var last driver.Block
+		for block := range blocks {
+			last = block
+		}
+		require.Error(t, last.Err)
+		assert.Contains(t, last.Err.Error(), "Memory limit exceeded")
+		assert.Empty(t, last.Columns)
+	})
+
+	t.Run("receiver gives up", func(t *testing.T) {
+		srv := newFakeServer().blocks(t, 5, 3)
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		blocks, err := conn.(driver.BlocksConn).QueryBlocks(ctx, "SELECT id, name, code FROM t")
+		require.NoError(t, err)
+		<-blocks
+		cancel()
+		for range blocks {
+		}
+	})
+}
+
+func TestQueryErrorAfterLastBlock(t *testing.T) {
+	block := &proto.Block{}
+	require.NoError(t, block.AddColumn("id", "UInt64"))
+	require.NoError(t, block.Append(uint64(1)))
+	srv := newFakeServer().data(block).data(block).raise(241, "Memory limit exceeded")
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the error is queued while the stream is closed, which Close used to miss now and then
+	for i := 0; i < 20; i++ {
+		rows, err := conn.Query(context.Background(), "SELECT id FROM t")
+		require.NoError(t, err)
+		for rows.Next() {
+		}
+		require.Error(t, rows.Err())
+		assert.True(t, IsErrorCode(rows.Err(), CodeMemoryLimitExceeded))
+	}
+}
+
+func TestQueryExtremes(t *testing.T) {
+	newBlock := func(values ...uint64) *proto.Block {
+		block := &proto.Block{}
+		require.NoError(t, block.AddColumn("n", "UInt64"))
+		for _, v := range values {
+			require.NoError(t, block.Append(v))
+		}
+		return block
+	}
+	srv := newFakeServer().
+		data(newBlock(3, 1)).
+		data(newBlock(7)).
+		block(proto.ServerTotals, newBlock(11)).
+		block(proto.ServerExtremes, newBlock(1, 7)).
+		endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT n FROM t SETTINGS extremes = 1")
+	require.NoError(t, err)
+	var min, max uint64
+	assert.ErrorIs(t, rows.(driver.ExtremesRows).Extremes([]any{&min}, []any{&max}), sql.ErrNoRows, "the extremes follow the data")
+	var got []uint64
+	for rows.Next() {
+		var n uint64
+		require.NoError(t, rows.Scan(&n))
+		got = append(got, n)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []uint64{3, 1, 7}, got, "the extremes aren't part of the rows")
+
+	require.NoError(t, rows.(driver.ExtremesRows).Extremes([]any{&min}, []any{&max}))
+	assert.Equal(t, [2]uint64{1, 7}, [2]uint64{min, max})
+	max = 0
+	require.NoError(t, rows.(driver.ExtremesRows).Extremes(nil, []any{&max}))
+	assert.Equal(t, uint64(7), max)
+
+	var totals uint64
+	require.NoError(t, rows.Totals(&totals))
+	assert.Equal(t, uint64(11), totals)
+}
+
+func TestQueryErrorBetweenBlocks(t *testing.T) {
+	first, second := &proto.Block{}, &proto.Block{}
+	require.NoError(t, first.AddColumn("id", "UInt64"))
+	require.NoError(t, second.AddColumn("id", "UInt64"))
+	require.NoError(t, first.Append(uint64(1)))
+	require.NoError(t, first.Append(uint64(2)))
+	require.NoError(t, second.Append(uint64(3)))
+	srv := newFakeServer().data(first).data(second).data(second).raise(CodeMemoryLimitExceeded, "Memory limit exceeded")
+	conn, err := Open(&Options{DialContext: srv.dial, BlockBufferSize: 4})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for i := 0; i < 20; i++ {
+		result, err := conn.Query(context.Background(), "SELECT id FROM t")
+		require.NoError(t, err)
+		// the blocks before the exception and the exception itself are all queued by now
+		require.Eventually(t, func() bool { return len(result.(*rows).errors) == 1 }, time.Second, time.Millisecond)
+		var ids []uint64
+		for result.Next() {
+			var id uint64
+			require.NoError(t, result.Scan(&id))
+			ids = append(ids, id)
+		}
+		assert.Equal(t, []uint64{1, 2, 3, 3}, ids, "the rows before the exception are read first")
+		var exception *Exception
+		require.ErrorAs(t, result.Err(), &exception)
+		assert.Equal(t, int32(CodeMemoryLimitExceeded), exception.Code)
+		assert.False(t, result.Next(), "the iteration ends with the exception")
+	}
+}
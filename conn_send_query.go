@@ -26,6 +26,10 @@ import (
 func (c *connect) sendQuery(body string, o *QueryOptions) error {
 	c.debugf("[send query] compression=%q %s", c.compression, body)
 	c.buffer.PutByte(proto.ClientQuery)
+	quotaKey := o.quotaKey
+	if quotaKey == "" {
+		quotaKey = c.opt.QuotaKey
+	}
 	q := proto.Query{
 		ClientTCPProtocolVersion: ClientTCPProtocolVersion,
 		ClientName:               c.opt.ClientInfo.String(),
@@ -33,10 +37,10 @@ func (c *connect) sendQuery(body string, o *QueryOptions) error {
 		ID:                       o.queryID,
 		Body:                     body,
 		Span:                     o.span,
-		QuotaKey:                 o.quotaKey,
+		QuotaKey:                 quotaKey,
 		Compression:              c.compression != CompressionNone,
 		InitialAddress:           c.conn.LocalAddr().String(),
-		Settings:                 c.settings(o.settings),
+		Settings:                 c.settings(o.settings, o.maxExecutionTime),
 		Parameters:               parametersToProtoParameters(o.parameters),
 	}
 	if err := q.Encode(c.buffer, c.revision); err != nil {
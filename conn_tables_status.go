@@ -0,0 +1,73 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+)
+
+// Connection::getTablesStatus
+// https://github.com/ClickHouse/ClickHouse/blob/master/src/Client/Connection.cpp
+func (c *connect) tablesStatus(ctx context.Context, tables []TableName) (map[TableName]TableStatus, error) {
+	if err := c.begin("tablesStatus"); err != nil {
+		return nil, err
+	}
+	defer c.end()
+	c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	if deadline, ok := ctx.Deadline(); ok {
+		c.setDeadline(deadline)
+		defer c.setDeadline(time.Time{})
+	}
+	request := proto.TablesStatusRequest{Tables: tables}
+	if err := request.Encode(c.buffer, c.revision); err != nil {
+		c.buffer.Reset()
+		return nil, err
+	}
+	c.debugf("[tables status] -> %d tables", len(tables))
+	if err := c.flush(); err != nil {
+		return nil, err
+	}
+
+	var (
+		options = queryOptions(ctx)
+		on      = options.onProcess()
+	)
+	for {
+		packet, err := c.reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch packet {
+		case proto.ServerTablesStatus:
+			var response proto.TablesStatusResponse
+			if err := response.Decode(c.reader); err != nil {
+				return nil, err
+			}
+			c.debugf("[tables status] <- %d tables", len(response.Tables))
+			return response.Tables, nil
+		default:
+			if err := c.handle(ctx, packet, on); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTablesStatus(t *testing.T) {
+	tables := []TableName{{Database: "db", Table: "events"}, {Database: "db", Table: "users"}}
+
+	t.Run("replica lag", func(t *testing.T) {
+		srv := newFakeServer().progress(1, 0)
+		srv.script.PutByte(proto.ServerTablesStatus)
+		srv.script.PutUVarInt(2)
+		srv.script.PutString("db")
+		srv.script.PutString("events")
+		srv.script.PutBool(true)
+		srv.script.PutUVarInt(42)
+		srv.script.PutString("db")
+		srv.script.PutString("users")
+		srv.script.PutBool(false)
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		status, err := conn.(driver.TablesStatusConn).TablesStatus(context.Background(), tables)
+		require.NoError(t, err)
+		assert.Equal(t, map[TableName]TableStatus{
+			tables[0]: {IsReplicated: true, AbsoluteDelay: 42},
+			tables[1]: {},
+		}, status)
+		assert.True(t, bytes.Contains(srv.sent(), []byte("\x05\x02\x02db\x06events\x02db\x05users")))
+	})
+
+	t.Run("exception", func(t *testing.T) {
+		srv := newFakeServer().raise(60, "Table db.events doesn't exist")
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.(driver.TablesStatusConn).TablesStatus(context.Background(), tables)
+		var exception *Exception
+		require.ErrorAs(t, err, &exception)
+		assert.EqualValues(t, 60, exception.Code)
+	})
+}
@@ -0,0 +1,997 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDialUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clickhouse.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	srv := newFakeServer().pong()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		srv.serve(conn)
+	}()
+
+	conn, err := Open(&Options{Addr: []string{"unix://" + path}})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.Ping(context.Background()))
+}
+
+func TestDialContext(t *testing.T) {
+	srv := newFakeServer().pong()
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+	conn, err := Open(&Options{
+		Addr:             []string{"hung:9000", "in-memory:9000"},
+		ConnOpenStrategy: ConnOpenInOrder,
+		DialTimeout:      50 * time.Millisecond,
+		DialContext: func(ctx context.Context, addr string) (net.Conn, error) {
+			mu.Lock()
+			dialed = append(dialed, addr)
+			mu.Unlock()
+			if addr == "hung:9000" {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			client, server := net.Pipe()
+			go srv.serve(server)
+			return client, nil
+		},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the context of the ping has no deadline, the address that hangs gives up after DialTimeout
+	require.NoError(t, conn.Ping(context.Background()))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"hung:9000", "in-memory:9000"}, dialed)
+}
+
+func TestDialProxy(t *testing.T) {
+	// serveSOCKS5 accepts a single connection, answers the no authentication handshake of SOCKS5
+	// and hands the connection to serve as if the proxy connected to the requested target.
+	serveSOCKS5 := func(t *testing.T, serve func(net.Conn)) (*url.URL, <-chan string) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+		targets := make(chan string, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			greeting := make([]byte, 3) // version, one method, no authentication
+			if _, err := io.ReadFull(conn, greeting); err != nil {
+				return
+			}
+			conn.Write([]byte{5, 0})
+			request := make([]byte, 5) // version, CONNECT, reserved, domain name and its length
+			if _, err := io.ReadFull(conn, request); err != nil {
+				return
+			}
+			target := make([]byte, request[4]+2)
+			if _, err := io.ReadFull(conn, target); err != nil {
+				return
+			}
+			port := binary.BigEndian.Uint16(target[len(target)-2:])
+			targets <- net.JoinHostPort(string(target[:len(target)-2]), strconv.Itoa(int(port)))
+			conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+			serve(conn)
+		}()
+		return &url.URL{Scheme: "socks5", Host: listener.Addr().String()}, targets
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		proxy, targets := serveSOCKS5(t, newFakeServer().pong().serve)
+		conn, err := Open(&Options{Addr: []string{"clickhouse.internal:9000"}, Proxy: proxy})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Ping(context.Background()))
+		assert.Equal(t, "clickhouse.internal:9000", <-targets)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		certificate, pool := selfSignedCertificate(t, "clickhouse.internal")
+		srv := newFakeServer().pong()
+		proxy, targets := serveSOCKS5(t, func(conn net.Conn) {
+			srv.serve(tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{certificate}}))
+		})
+		conn, err := Open(&Options{
+			Addr:  []string{"clickhouse.internal:9440"},
+			Proxy: proxy,
+			// verified against the name of the server rather than the proxy
+			TLS: &tls.Config{RootCAs: pool},
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Ping(context.Background()))
+		assert.Equal(t, "clickhouse.internal:9440", <-targets)
+	})
+}
+
+// selfSignedCertificate returns a certificate for host and a pool that trusts it.
+func selfSignedCertificate(t *testing.T, host string) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestHandshakeDatabase(t *testing.T) {
+	t.Run("non-default database", func(t *testing.T) {
+		srv := newFakeServer().pong()
+		conn, err := Open(&Options{DialContext: srv.dial, Auth: Auth{Database: "analytics"}})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Ping(context.Background()))
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		assert.Equal(t, "analytics", srv.database)
+	})
+
+	t.Run("unknown database", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.exception = &proto.Exception{Code: 81, Name: "DB::Exception", Message: "Database missing does not exist"}
+		conn, err := Open(&Options{DialContext: srv.dial, Auth: Auth{Database: "missing"}})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		err = conn.Ping(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `database "missing"`)
+		var exception *Exception
+		require.True(t, errors.As(err, &exception))
+		assert.EqualValues(t, 81, exception.Code)
+	})
+}
+
+func TestHandshakeAuth(t *testing.T) {
+	for _, dsn := range []string{
+		"clickhouse://default@127.0.0.1",
+		"clickhouse://default:@127.0.0.1",
+		"clickhouse://127.0.0.1?username=default&password=",
+	} {
+		t.Run("passwordless user "+dsn, func(t *testing.T) {
+			opt, err := ParseDSN(dsn)
+			require.NoError(t, err)
+			srv := newFakeServer().pong()
+			srv.password = "unset"
+			opt.DialContext = srv.dial
+			conn, err := Open(opt)
+			require.NoError(t, err)
+			defer conn.Close()
+			require.NoError(t, conn.Ping(context.Background()))
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			assert.Equal(t, "default", srv.username)
+			assert.Equal(t, "", srv.password, "an empty password is sent")
+		})
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.exception = &proto.Exception{
+			Code:    516,
+			Name:    "DB::Exception",
+			Message: "default: Authentication failed: password is incorrect, or there is no user with such name.",
+		}
+		conn, err := Open(&Options{DialContext: srv.dial, Auth: Auth{Password: "wrong"}})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		err = conn.Ping(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `authentication of user "default" failed`)
+		var exception *Exception
+		require.True(t, errors.As(err, &exception))
+		assert.EqualValues(t, 516, exception.Code)
+	})
+}
+
+// closeCounter counts the connections closed, e.g. to tell a failed dial from one that leaks.
+type closeCounter struct {
+	net.Conn
+	closed *atomic.Int64
+}
+
+func (c closeCounter) Close() error {
+	c.closed.Add(1)
+	return c.Conn.Close()
+}
+
+func TestDialClosesOnHandshakeError(t *testing.T) {
+	srv := newFakeServer()
+	srv.exception = &proto.Exception{Code: CodeAuthenticationFailed, Name: "DB::Exception", Message: "wrong password"}
+	var closed atomic.Int64
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := srv.dial(ctx, addr)
+		return closeCounter{Conn: conn, closed: &closed}, err
+	}
+	conn, err := Open(&Options{DialContext: dial, Auth: Auth{Password: "wrong"}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for i := 1; i <= 3; i++ {
+		require.Error(t, conn.Ping(context.Background()))
+		assert.EqualValues(t, i, closed.Load(), "every failed dial is closed")
+	}
+}
+
+func TestDebugTrace(t *testing.T) {
+	ping := func(trace bool) string {
+		var (
+			logs   strings.Builder
+			srv    = newFakeServer().pong()
+			debugf = func(format string, v ...any) {
+				fmt.Fprintf(&logs, format+"\n", v...)
+			}
+		)
+		conn, err := Open(&Options{DialContext: srv.dial, Debug: true, Debugf: debugf, Trace: trace})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Ping(context.Background()))
+		return logs.String()
+	}
+
+	logs := ping(true)
+	assert.Contains(t, logs, "[write]")
+	assert.Contains(t, logs, "[read]")
+	// the server hello starts with the packet type followed by the server name
+	assert.Contains(t, logs, "00 0a 43 6c 69 63 6b 48  6f 75 73 65")
+
+	logs = ping(false)
+	assert.NotContains(t, logs, "[write]")
+	assert.NotContains(t, logs, "[read]")
+}
+
+func TestDebugTraceRedactsPassword(t *testing.T) {
+	var (
+		logs   strings.Builder
+		srv    = newFakeServer().endOfStream()
+		debugf = func(format string, v ...any) {
+			fmt.Fprintf(&logs, format+"\n", v...)
+		}
+	)
+	conn, err := Open(&Options{
+		DialContext: srv.dial,
+		Auth:        Auth{Username: "default", Password: "s3cr3t"},
+		Debug:       true,
+		Debugf:      debugf,
+		Trace:       true,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.Exec(context.Background(), "ALTER USER u IDENTIFIED BY 's3cr3t'"))
+
+	// the text columns of the dump, joined, as the password may be split over two lines
+	var text strings.Builder
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if start, end := strings.IndexByte(line, '|'), strings.LastIndexByte(line, '|'); start != -1 && end > start {
+			text.WriteString(line[start+1 : end])
+		}
+	}
+	assert.Contains(t, text.String(), "default.******")
+	assert.Equal(t, 1, strings.Count(text.String(), "s3cr3t"), "only the hello is masked, not the queries")
+	assert.Contains(t, text.String(), "IDENTIFIED BY 's3cr3t'")
+}
+
+func TestMaxStringSize(t *testing.T) {
+	t.Run("String values", func(t *testing.T) {
+		value := strings.Repeat("x", 1025)
+		for _, chType := range []string{"String", "Array(Nullable(String))", "LowCardinality(String)", "Map(String, UInt8)", "Tuple(String, UInt8)"} {
+			block := &proto.Block{}
+			require.NoError(t, block.AddColumn("v", column.Type(chType)))
+			switch chType {
+			case "String", "LowCardinality(String)":
+				require.NoError(t, block.Append(value))
+			case "Array(Nullable(String))":
+				require.NoError(t, block.Append([]*string{&value}))
+			case "Map(String, UInt8)":
+				require.NoError(t, block.Append(map[string]uint8{value: 1}))
+			default:
+				require.NoError(t, block.Append([]any{value, uint8(1)}))
+			}
+			read := func(maxStringSize int) error {
+				srv := newFakeServer().data(block).endOfStream()
+				conn, err := Open(&Options{DialContext: srv.dial, MaxStringSize: maxStringSize})
+				require.NoError(t, err)
+				defer conn.Close()
+				rows, err := conn.Query(context.Background(), "SELECT v")
+				if err != nil {
+					return err
+				}
+				defer rows.Close()
+				for rows.Next() {
+				}
+				return rows.Err()
+			}
+			assert.ErrorIs(t, read(1024), proto.ErrStringTooLarge, chType)
+			assert.NoError(t, read(1025), chType)
+		}
+	})
+
+	testCases := []struct {
+		name          string
+		maxStringSize int
+		length        uint64
+	}{
+		{"absurd length with default limit", 0, 1 << 40},
+		{"configured limit", 1024, 1025},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newFakeServer()
+			srv.script.PutByte(proto.ServerData)
+			srv.script.PutString("")
+			// block info
+			srv.script.PutUVarInt(1)
+			srv.script.PutBool(false)
+			srv.script.PutUVarInt(2)
+			srv.script.PutInt32(-1)
+			srv.script.PutUVarInt(0)
+			// one column, one row and a column name length prefix with no data behind it
+			srv.script.PutUVarInt(1)
+			srv.script.PutUVarInt(1)
+			srv.script.PutUVarInt(tc.length)
+
+			conn, err := Open(&Options{DialContext: srv.dial, MaxStringSize: tc.maxStringSize})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Query(context.Background(), "SELECT 1")
+			require.Error(t, err)
+			assert.ErrorIs(t, err, proto.ErrStringTooLarge)
+		})
+	}
+}
+
+func TestQuotaKey(t *testing.T) {
+	exec := func(ctx context.Context) *fakeServer {
+		srv := newFakeServer().endOfStream()
+		conn, err := Open(&Options{DialContext: srv.dial, QuotaKey: "tenant_a"})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+		return srv
+	}
+
+	t.Run("connection default", func(t *testing.T) {
+		srv := exec(context.Background())
+		srv.mu.Lock()
+		assert.Equal(t, "tenant_a", srv.quotaKey)
+		srv.mu.Unlock()
+		assert.True(t, bytes.Contains(srv.sent(), []byte("tenant_a")))
+	})
+
+	t.Run("query override", func(t *testing.T) {
+		srv := exec(Context(context.Background(), WithQuotaKey("tenant_b")))
+		srv.mu.Lock()
+		assert.Equal(t, "tenant_a", srv.quotaKey)
+		srv.mu.Unlock()
+		sent := srv.sent()
+		assert.True(t, bytes.Contains(sent, []byte("tenant_b")))
+		assert.False(t, bytes.Contains(sent, []byte("tenant_a")))
+	})
+}
+
+func TestNegotiatedRevision(t *testing.T) {
+	testCases := []struct {
+		name     string
+		revision uint64
+	}{
+		{"server above client", ClientTCPProtocolVersion + 10},
+		{"server below client", proto.DBMS_MIN_REVISION_WITH_CLIENT_WRITE_INFO},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newFakeServer()
+			srv.revision = tc.revision
+			srv.blocks(t, 2, 10)
+			conn, err := Open(&Options{DialContext: srv.dial})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			version, err := conn.ServerVersion()
+			require.NoError(t, err)
+			assert.Equal(t, tc.revision, version.Revision)
+			assert.Equal(t, min(tc.revision, ClientTCPProtocolVersion), version.NegotiatedRevision)
+			assert.Equal(t, "fake", version.DisplayName)
+
+			rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+			require.NoError(t, err)
+			var n int
+			for rows.Next() {
+				n++
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, 20, n)
+		})
+	}
+}
+
+func TestServerVersionLocation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		revision uint64
+		timezone string
+		expected string
+	}{
+		{"server timezone", ClientTCPProtocolVersion, "Europe/Berlin", "Europe/Berlin"},
+		{"no timezone before its revision", proto.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE - 1, "Europe/Berlin", "UTC"},
+		{"unknown timezone", ClientTCPProtocolVersion, "Mars/Olympus_Mons", "UTC"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				logs strings.Builder
+				srv  = newFakeServer()
+			)
+			srv.revision, srv.timezone = tc.revision, tc.timezone
+			conn, err := Open(&Options{
+				DialContext: srv.dial,
+				Debug:       true,
+				Debugf:      func(format string, v ...any) { fmt.Fprintf(&logs, format+"\n", v...) },
+			})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			version, err := conn.ServerVersion()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, version.Location().String())
+			if tc.expected != tc.timezone && version.TimezoneName != "" {
+				assert.Contains(t, logs.String(), `WARNING: unknown server timezone "Mars/Olympus_Mons", using UTC`)
+			} else {
+				assert.NotContains(t, logs.String(), "WARNING: unknown server timezone")
+			}
+		})
+	}
+}
+
+func TestOldServerRevision(t *testing.T) {
+	srv := newFakeServer().endOfStream()
+	// older than the quota key in the client info, the addendum and server side parameters
+	srv.revision = proto.DBMS_MIN_REVISION_WITH_QUOTA_KEY_IN_CLIENT_INFO - 1
+	conn, err := Open(&Options{DialContext: srv.dial, QuotaKey: "tenant_quota"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.Exec(context.Background(), "SELECT @x", Named("x", "bound")))
+	sent := string(srv.sent())
+	assert.Contains(t, sent, "SELECT 'bound'", "parameters are bound by the client")
+	assert.NotContains(t, sent, "tenant_quota")
+	assert.NotContains(t, sent, "param_x")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Empty(t, srv.quotaKey, "no addendum")
+}
+
+func TestServerTimeouts(t *testing.T) {
+	srv := newFakeServer().endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial, ReadTimeout: time.Minute, WriteTimeout: time.Minute})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := Context(context.Background(), WithServerTimeouts(30*time.Second, 45*time.Second, 2*time.Minute))
+	require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+	sent := srv.sent()
+	// the server side timeouts are sent as settings, the socket deadlines aren't
+	for _, setting := range []string{"send_timeout", "receive_timeout", "max_execution_time"} {
+		assert.True(t, bytes.Contains(sent, []byte(setting)), setting)
+	}
+	assert.False(t, bytes.Contains(sent, []byte("read_timeout")))
+}
+
+func TestDeadlineMaxExecutionTime(t *testing.T) {
+	srv := newFakeServer().endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 36500*time.Millisecond)
+	defer cancel()
+	require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+	sent := srv.sent()
+	i := bytes.Index(sent, []byte("max_execution_time"))
+	require.NotEqual(t, -1, i, "the deadline is sent to the server")
+	assert.True(t, bytes.Contains(sent[i:], []byte("37")), "the remaining time is rounded up to whole seconds")
+}
+
+func TestDeadlineKeepsMaxExecutionTimeSetting(t *testing.T) {
+	maxExecutionTime := func(c *connect, query Settings) any {
+		for _, s := range c.settings(query, 10) {
+			if s.Key == "max_execution_time" {
+				return s.Value
+			}
+		}
+		return nil
+	}
+
+	var (
+		defaults defaultSettings
+		c        = &connect{opt: &Options{}, defaults: &defaults}
+	)
+	assert.Equal(t, 10, maxExecutionTime(c, nil), "the deadline is sent when nothing sets the setting")
+
+	// a value set at any level is the user's choice, even when it's looser than the deadline
+	c.opt.Settings = Settings{"max_execution_time": 60}
+	assert.Equal(t, 60, maxExecutionTime(c, nil))
+	c.opt.Settings = nil
+	defaults.set("max_execution_time", "30")
+	assert.Equal(t, "30", maxExecutionTime(c, nil))
+	defaults.clear()
+	assert.Equal(t, 120, maxExecutionTime(c, Settings{"max_execution_time": 120}))
+	assert.Equal(t, 5, maxExecutionTime(c, Settings{"max_execution_time": 5}))
+
+	t.Run("HTTP", func(t *testing.T) {
+		var (
+			mu    sync.Mutex
+			sent  []string
+			inner = &fakeHTTPServer{}
+		)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			sent = append(sent, r.URL.Query().Get("max_execution_time"))
+			mu.Unlock()
+			inner.ServeHTTP(w, r)
+		}))
+		defer ts.Close()
+
+		query := func(settings Settings, set func(driver.Conn)) string {
+			conn, err := Open(&Options{
+				Addr:     []string{strings.TrimPrefix(ts.URL, "http://")},
+				Protocol: HTTP,
+				Settings: settings,
+			})
+			require.NoError(t, err)
+			defer conn.Close()
+			if set != nil {
+				set(conn)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+			mu.Lock()
+			defer mu.Unlock()
+			return sent[len(sent)-1]
+		}
+		assert.Equal(t, "10", query(nil, nil))
+		assert.Equal(t, "60", query(Settings{"max_execution_time": 60}, nil))
+		assert.Equal(t, "30", query(nil, func(conn driver.Conn) { conn.(driver.SettingsConn).SetSetting("max_execution_time", 30) }))
+	})
+}
+
+// writeDeadlines keeps the write deadline in effect at every read and write from the first query
+// packet on, the handshake and the addendum are written while dialing, before any query deadline.
+type writeDeadlines struct {
+	net.Conn
+	mu        sync.Mutex
+	current   time.Time
+	query     bool
+	deadlines []time.Time
+}
+
+func (c *writeDeadlines) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.current = t
+	c.mu.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *writeDeadlines) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.current = t
+	c.mu.Unlock()
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *writeDeadlines) record(query bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.query = c.query || query; c.query {
+		c.deadlines = append(c.deadlines, c.current)
+	}
+}
+
+func (c *writeDeadlines) Read(p []byte) (int, error) {
+	c.record(false)
+	return c.Conn.Read(p)
+}
+
+func (c *writeDeadlines) Write(p []byte) (int, error) {
+	c.record(len(p) != 0 && p[0] == proto.ClientQuery)
+	return c.Conn.Write(p)
+}
+
+func TestWriteTimeoutKeepsContextDeadline(t *testing.T) {
+	var (
+		tracked *writeDeadlines
+		srv     = newFakeServer().progress(1, 1).endOfStream()
+		dial    = func(ctx context.Context, addr string) (net.Conn, error) {
+			conn, err := srv.dial(ctx, addr)
+			tracked = &writeDeadlines{Conn: conn}
+			return tracked, err
+		}
+	)
+	conn, err := Open(&Options{DialContext: dial, DialTimeout: 10 * time.Second, WriteTimeout: time.Hour})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	require.NoError(t, conn.Exec(ctx, "INSERT INTO t SELECT * FROM s"))
+
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+	require.NotEmpty(t, tracked.deadlines)
+	for _, d := range tracked.deadlines {
+		// the write timeout doesn't extend past the deadline of the context nor clear it once a write is done
+		assert.False(t, d.IsZero(), "no write deadline")
+		assert.False(t, d.After(deadline), "write deadline %v after %v", d, deadline)
+	}
+}
+
+func TestSpanFromContext(t *testing.T) {
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	// the server reads the ids as little endian 64 bit words
+	traceID := []byte{8, 7, 6, 5, 4, 3, 2, 1, 16, 15, 14, 13, 12, 11, 10, 9}
+	ctx := trace.ContextWithSpanContext(context.Background(), span)
+
+	exec := func(revision uint64, ctx context.Context) []byte {
+		srv := newFakeServer().endOfStream()
+		srv.revision = revision
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+		return srv.sent()
+	}
+
+	assert.True(t, bytes.Contains(exec(ClientTCPProtocolVersion, ctx), traceID), "the span of the context is sent")
+	assert.False(t, bytes.Contains(exec(proto.DBMS_MIN_REVISION_WITH_OPENTELEMETRY-1, ctx), traceID), "older servers don't take a span")
+
+	other := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{0xff}, SpanID: trace.SpanID{0xff}})
+	sent := exec(ClientTCPProtocolVersion, Context(ctx, WithSpan(other)))
+	assert.False(t, bytes.Contains(sent, traceID), "WithSpan takes precedence over the context")
+}
+
+func TestClientRevision(t *testing.T) {
+	advertise := func(revision uint64) (*fakeServer, driver.Conn) {
+		srv := newFakeServer().endOfStream()
+		srv.timezone = "Europe/Berlin"
+		conn, err := Open(&Options{DialContext: srv.dial, ClientRevision: revision})
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return srv, conn
+	}
+
+	srv, conn := advertise(proto.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE - 1)
+	require.NoError(t, conn.Exec(context.Background(), "SELECT 1"))
+	assert.EqualValues(t, proto.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE-1, srv.clientRevision)
+	version, err := conn.ServerVersion()
+	require.NoError(t, err)
+	assert.Empty(t, version.TimezoneName, "the server time zone isn't negotiated")
+
+	srv, conn = advertise(ClientTCPProtocolVersion + 1)
+	require.NoError(t, conn.Exec(context.Background(), "SELECT 1"))
+	assert.EqualValues(t, ClientTCPProtocolVersion, srv.clientRevision, "a newer revision than the driver speaks isn't advertised")
+
+	_, conn = advertise(proto.DBMS_MIN_REVISION_WITH_CLIENT_INFO - 1)
+	assert.ErrorContains(t, conn.Exec(context.Background(), "SELECT 1"), "older than the oldest supported")
+}
+
+func TestClientName(t *testing.T) {
+	hello := func(dsn string) (*fakeServer, string) {
+		opts, err := ParseDSN(dsn)
+		require.NoError(t, err)
+		srv := newFakeServer().endOfStream()
+		opts.DialContext = srv.dial
+		conn, err := Open(opts)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Exec(context.Background(), "SELECT 1"))
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return srv, srv.clientName
+	}
+
+	srv, name := hello("tcp://127.0.0.1:9000?client_name=billing-exporter")
+	assert.Equal(t, "billing-exporter", name)
+	assert.True(t, bytes.Contains(srv.sent(), []byte("billing-exporter")), "the query client info carries the name too")
+
+	_, name = hello("tcp://127.0.0.1:9000")
+	assert.True(t, strings.HasPrefix(name, ClientName+"/"), name)
+}
+
+func TestLocationOption(t *testing.T) {
+	value := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	read := func(location *time.Location) time.Time {
+		block := &proto.Block{}
+		require.NoError(t, block.AddColumn("t", "DateTime"))
+		require.NoError(t, block.Append(value))
+
+		srv := newFakeServer().data(block).endOfStream()
+		srv.timezone = "Europe/Berlin"
+		conn, err := Open(&Options{DialContext: srv.dial, Location: location})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var t0 time.Time
+		require.NoError(t, conn.QueryRow(context.Background(), "SELECT t").Scan(&t0))
+		return t0
+	}
+
+	t.Run("server time zone", func(t *testing.T) {
+		t0 := read(nil)
+		assert.Equal(t, "Europe/Berlin", t0.Location().String())
+		assert.Equal(t, "2024-01-01 13:00:00", t0.Format(time.DateTime))
+	})
+	t.Run("UTC", func(t *testing.T) {
+		t0 := read(time.UTC)
+		assert.Equal(t, "UTC", t0.Location().String())
+		assert.Equal(t, "2024-01-01 12:00:00", t0.Format(time.DateTime))
+	})
+}
+
+func TestDefaultSettings(t *testing.T) {
+	settings := func(c *connect, query Settings) Settings {
+		merged := Settings{}
+		for _, s := range c.settings(query, 0) {
+			merged[s.Key] = s.Value
+		}
+		return merged
+	}
+
+	var (
+		defaults defaultSettings
+		c        = &connect{
+			opt:      &Options{Settings: Settings{"max_threads": 1, "readonly": 1}},
+			defaults: &defaults,
+		}
+	)
+	assert.Equal(t, Settings{"max_threads": 1, "readonly": 1}, settings(c, nil))
+
+	defaults.set("max_threads", "4")
+	defaults.set("max_block_size", 1024)
+	assert.Equal(t, Settings{"max_threads": "4", "readonly": 1, "max_block_size": 1024}, settings(c, nil))
+	assert.Equal(t, Settings{"max_threads": 8, "readonly": 1, "max_block_size": 1024}, settings(c, Settings{"max_threads": 8}))
+
+	defaults.clear()
+	assert.Equal(t, Settings{"max_threads": 1, "readonly": 1}, settings(c, nil))
+
+	c.opt.MaxBlockRows = 1000
+	assert.Equal(t, Settings{"max_threads": 1, "readonly": 1}, settings(c, nil), "the decode limit isn't sent as max_block_size")
+	c.opt.MaxBlockRows = 0
+
+	// boolean values are sent as the 1 and 0 the server expects, whichever level sets them, custom settings are kept as is
+	c.opt.Settings = Settings{"max_threads": 1, "readonly": true}
+	defaults.set("use_uncompressed_cache", "False")
+	assert.Equal(t, Settings{"max_threads": 1, "readonly": 1, "use_uncompressed_cache": 0, "log_queries": 1, "log_comment": "true"},
+		settings(c, Settings{"log_queries": "TRUE", "log_comment": CustomSetting{"true"}}))
+	defaults.clear()
+
+	t.Run("applied to queries", func(t *testing.T) {
+		srv := newFakeServer().endOfStream()
+		conn, err := Open(&Options{DialContext: srv.dial})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		conn.(driver.SettingsConn).SetSetting("max_threads", "4")
+		require.NoError(t, conn.Exec(context.Background(), "SELECT 1"))
+		assert.True(t, bytes.Contains(srv.sent(), []byte("max_threads")))
+	})
+
+	t.Run("query settings over DSN settings", func(t *testing.T) {
+		srv := newFakeServer().endOfStream()
+		conn, err := Open(&Options{
+			DialContext: srv.dial,
+			Settings:    Settings{"max_memory_usage": 1111111, "max_threads": 3},
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx := Context(context.Background(), WithSettings(Settings{"max_memory_usage": 2222222}))
+		require.NoError(t, conn.Exec(ctx, "SELECT 1"))
+		sent := srv.sent()
+		assert.True(t, bytes.Contains(sent, []byte("2222222")), "the query setting is sent")
+		assert.False(t, bytes.Contains(sent, []byte("1111111")), "the DSN setting is overridden")
+		assert.True(t, bytes.Contains(sent, []byte("max_threads")), "other DSN settings are kept")
+	})
+}
+
+func TestIgnoreUnknownSettings(t *testing.T) {
+	c := &connect{opt: &Options{Settings: Settings{
+		"max_threads":       1,
+		"wait_end_of_query": 1,
+		"log_comment":       CustomSetting{"etl"},
+	}}}
+	important := func() map[string]bool {
+		settings := map[string]bool{}
+		for _, s := range c.settings(nil, 0) {
+			settings[s.Key] = s.Important
+		}
+		return settings
+	}
+	assert.Equal(t, map[string]bool{"max_threads": true, "wait_end_of_query": true, "log_comment": false}, important(),
+		"unchanged unless set")
+
+	c.opt.IgnoreUnknownSettings = true
+	assert.Equal(t, map[string]bool{"max_threads": false, "log_comment": false}, important(),
+		"the parameters of the HTTP interface are left out, the rest may be skipped by the server")
+}
+
+func TestExecCancel(t *testing.T) {
+	// a slow INSERT ... SELECT reports progress and then keeps the client waiting
+	srv := newFakeServer().progress(10, 10)
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err = conn.Exec(ctx, "INSERT INTO t SELECT number FROM numbers(1e12)")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second, "the read waiting for the server is interrupted")
+	assert.Eventually(t, func() bool {
+		sent := srv.sent()
+		return len(sent) != 0 && sent[len(sent)-1] == proto.ClientCancel
+	}, time.Second, 10*time.Millisecond, "the server is told to cancel the query")
+	assert.Equal(t, 0, conn.Stats().Idle, "the cancelled connection isn't reused")
+}
+
+func TestUseDatabase(t *testing.T) {
+	for query, expected := range map[string]string{
+		"USE other":        "other",
+		"use `other db`;":  "",
+		"  USE \"other\";": "other",
+		"USE `other`":      "other",
+	} {
+		database, ok := useDatabase(query)
+		if expected == "" {
+			assert.False(t, ok, query)
+			continue
+		}
+		assert.True(t, ok, query)
+		assert.Equal(t, expected, database, query)
+	}
+	_, ok := useDatabase("SELECT 1")
+	assert.False(t, ok)
+
+	countDials := func(srv *fakeServer, dials *int) func(context.Context, string) (net.Conn, error) {
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			*dials++
+			return srv.dial(ctx, addr)
+		}
+	}
+
+	t.Run("native", func(t *testing.T) {
+		var (
+			dials int
+			srv   = newFakeServer().endOfStream()
+		)
+		conn, err := Open(&Options{DialContext: countDials(srv, &dials), Auth: Auth{Database: "db"}})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.Exec(context.Background(), "USE other"))
+		require.NoError(t, conn.Exec(context.Background(), "SELECT 1"))
+		assert.Equal(t, 2, dials, "a connection switched to another database isn't reused")
+	})
+
+	t.Run("default database", func(t *testing.T) {
+		var (
+			dials int
+			srv   = newFakeServer().endOfStream().endOfStream()
+		)
+		conn, err := Open(&Options{DialContext: countDials(srv, &dials)})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.Exec(context.Background(), "USE default"))
+		require.NoError(t, conn.Exec(context.Background(), "SELECT 1"))
+		assert.Equal(t, 1, dials, "USE default keeps the database of a DSN without one")
+	})
+
+	t.Run("std", func(t *testing.T) {
+		var (
+			dials int
+			srv   = newFakeServer().endOfStream().endOfStream()
+		)
+		db := OpenDB(&Options{DialContext: countDials(srv, &dials), Auth: Auth{Database: "db"}})
+		defer db.Close()
+
+		ctx := context.Background()
+		session, err := db.Conn(ctx)
+		require.NoError(t, err)
+		_, err = session.ExecContext(ctx, "USE other")
+		require.NoError(t, err)
+		_, err = session.ExecContext(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, dials, "the session keeps its connection and so its database")
+		require.NoError(t, session.Close())
+
+		_, err = db.ExecContext(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, dials, "the pool starts over on the DSN database")
+	})
+}
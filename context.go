@@ -19,6 +19,8 @@ package clickhouse
 
 import (
 	"context"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2/ext"
@@ -33,6 +35,26 @@ var _contextOptionKey = &QueryOptions{
 
 type Settings map[string]any
 
+// settingValue returns a boolean setting value, a Go bool or "true" and "false" in any case, as the
+// 1 or 0 the server expects, as some settings reject "true". Other values are returned as is.
+func settingValue(v any) any {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return 1
+		case "false":
+			return 0
+		}
+	}
+	return v
+}
+
 // CustomSetting is a helper struct to distinguish custom settings from important ones.
 // For native protocol, is_important flag is set to value 0x02 (see https://github.com/ClickHouse/ClickHouse/blob/c873560fe7185f45eed56520ec7d033a7beb1551/src/Core/BaseSettings.h#L516-L521)
 // Only string value is supported until formatting logic that exists in ClickHouse is implemented in clickhouse-go. (https://github.com/ClickHouse/ClickHouse/blob/master/src/Core/Field.cpp#L312 and https://github.com/ClickHouse/clickhouse-go/issues/992)
@@ -62,6 +84,10 @@ type (
 		external        []*ext.Table
 		blockBufferSize uint8
 		userLocation    *time.Location
+		internal        bool
+		// maxExecutionTime is the time left until the context deadline in whole seconds, sent as
+		// max_execution_time unless a setting gives one
+		maxExecutionTime int
 	}
 )
 
@@ -100,6 +126,31 @@ func WithSettings(settings Settings) QueryOption {
 	}
 }
 
+// WithServerTimeouts sets the send_timeout, receive_timeout and max_execution_time settings of a query,
+// rounded up to whole seconds, a zero duration leaves the setting as is. These are enforced by the
+// server, which aborts the query when one is exceeded, unlike Options.ReadTimeout and Options.WriteTimeout
+// which are deadlines of the client socket.
+func WithServerTimeouts(send, receive, execution time.Duration) QueryOption {
+	return func(o *QueryOptions) error {
+		// the settings may be shared with other contexts through WithSettings, so they're copied
+		settings := make(Settings, len(o.settings)+3)
+		for k, v := range o.settings {
+			settings[k] = v
+		}
+		for name, timeout := range map[string]time.Duration{
+			"send_timeout":       send,
+			"receive_timeout":    receive,
+			"max_execution_time": execution,
+		} {
+			if timeout > 0 {
+				settings[name] = int(math.Ceil(timeout.Seconds()))
+			}
+		}
+		o.settings = settings
+		return nil
+	}
+}
+
 func WithParameters(params Parameters) QueryOption {
 	return func(o *QueryOptions) error {
 		o.parameters = params
@@ -156,28 +207,27 @@ func WithUserLocation(location *time.Location) QueryOption {
 	}
 }
 
-func ignoreExternalTables() QueryOption {
+// internalQuery marks the queries the driver runs for itself, which go without the external
+// tables of the context and aren't reported to Options.Metrics.
+func internalQuery() QueryOption {
 	return func(o *QueryOptions) error {
 		o.external = nil
+		o.internal = true
 		return nil
 	}
 }
 
 func Context(parent context.Context, options ...QueryOption) context.Context {
-	opt := queryOptions(parent)
+	opt := contextOptions(parent)
 	for _, f := range options {
 		f(&opt)
 	}
 	return context.WithValue(parent, _contextOptionKey, opt)
 }
 
-func queryOptions(ctx context.Context) QueryOptions {
+// contextOptions returns the options stored in ctx by Context.
+func contextOptions(ctx context.Context) QueryOptions {
 	if o, ok := ctx.Value(_contextOptionKey).(QueryOptions); ok {
-		if deadline, ok := ctx.Deadline(); ok {
-			if sec := time.Until(deadline).Seconds(); sec > 1 {
-				o.settings["max_execution_time"] = int(sec + 5)
-			}
-		}
 		return o
 	}
 	return QueryOptions{
@@ -185,6 +235,24 @@ func queryOptions(ctx context.Context) QueryOptions {
 	}
 }
 
+// queryOptions returns the options a query runs with. A context deadline is passed on to the
+// server as max_execution_time, rounded up to whole seconds, so the query is aborted there too,
+// unless the setting is given by the options, the connection or the query. Without WithSpan the
+// span of ctx, as started with the OpenTelemetry API, is sent so the trace carries on into the
+// query log of the server.
+func queryOptions(ctx context.Context) QueryOptions {
+	o := contextOptions(ctx)
+	if !o.span.IsValid() {
+		o.span = trace.SpanContextFromContext(ctx)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining > 0 {
+			o.maxExecutionTime = int(math.Ceil(remaining.Seconds()))
+		}
+	}
+	return o
+}
+
 func (q *QueryOptions) onProcess() *onProcess {
 	return &onProcess{
 		logs: func(logs []Log) {
@@ -20,6 +20,7 @@ package clickhouse
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -50,3 +51,86 @@ func TestContext(t *testing.T) {
 		},
 	)
 }
+
+func TestContextDeadlineMaxExecutionTime(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		expected int
+	}{
+		{
+			"no deadline",
+			func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			0,
+		},
+		{
+			"plain context deadline",
+			func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 2500*time.Millisecond)
+			},
+			3,
+		},
+		{
+			"sub-second deadline rounds up",
+			func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 200*time.Millisecond)
+			},
+			1,
+		},
+		{
+			"expired deadline",
+			func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			},
+			0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := tc.ctx()
+			defer cancel()
+			assert.Equal(t, tc.expected, queryOptions(ctx).maxExecutionTime)
+		})
+	}
+
+	t.Run("settings passed to the context are left untouched", func(t *testing.T) {
+		settings := Settings{"max_threads": 2, "max_execution_time": 120}
+		ctx, cancel := context.WithTimeout(Context(context.Background(), WithSettings(settings)), time.Minute)
+		defer cancel()
+
+		opts := queryOptions(ctx)
+		assert.Equal(t, 60, opts.maxExecutionTime)
+		assert.Equal(t, Settings{"max_threads": 2, "max_execution_time": 120}, opts.settings)
+	})
+}
+
+func TestWithServerTimeouts(t *testing.T) {
+	settings := Settings{"max_threads": 2}
+	ctx := Context(context.Background(), WithSettings(settings), WithServerTimeouts(10*time.Second, 1500*time.Millisecond, 0))
+	assert.Equal(t, Settings{"max_threads": 2, "send_timeout": 10, "receive_timeout": 2}, queryOptions(ctx).settings)
+	assert.Equal(t, Settings{"max_threads": 2}, settings)
+}
+
+func TestSettingValue(t *testing.T) {
+	testCases := []struct {
+		value    any
+		expected any
+	}{
+		{true, 1},
+		{false, 0},
+		{"true", 1},
+		{"FALSE", 0},
+		{"True", 1},
+		{1, 1},
+		{0, 0},
+		{"1", "1"},
+		{"0", "0"},
+		{"lz4", "lz4"},
+		{uint64(5), uint64(5)},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, settingValue(tc.value), "%#v", tc.value)
+	}
+}
@@ -28,20 +28,22 @@ import "context"
 // // do something else
 // defer stopCW()
 func contextWatchdog(ctx context.Context, callback func()) (cancel func()) {
-	exit := make(chan struct{})
-
+	var (
+		exit = make(chan struct{})
+		done = make(chan struct{})
+	)
 	go func() {
-		for {
-			select {
-			case <-exit:
-				return
-			case <-ctx.Done():
-				callback()
-			}
+		defer close(done)
+		select {
+		case <-exit:
+		case <-ctx.Done():
+			callback()
 		}
 	}()
 
+	// the callback is done running once cancel returns
 	return func() {
-		exit <- struct{}{}
+		close(exit)
+		<-done
 	}
 }
@@ -0,0 +1,69 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import "errors"
+
+// Codes of the server exceptions the driver and its callers tell apart, see Exception.Code, IsErrorCode
+// and https://github.com/ClickHouse/ClickHouse/blob/master/src/Common/ErrorCodes.cpp for the full list.
+const (
+	CodeUnknownIdentifier          = 47
+	CodeTableAlreadyExists         = 57
+	CodeUnknownTable               = 60
+	CodeSyntaxError                = 62
+	CodeUnknownDatabase            = 81
+	CodeTimeoutExceeded            = 159
+	CodeReadonly                   = 164
+	CodeTooManySimultaneousQueries = 202
+	CodeSocketTimeout              = 209
+	CodeNetworkError               = 210
+	CodeMemoryLimitExceeded        = 241
+	CodeTooManyParts               = 252
+	CodeAllConnectionTriesFailed   = 279
+	CodeQueryWasCancelled          = 394
+	// CodeAuthenticationFailed is a wrong password or a password sent for a user configured without one.
+	CodeAuthenticationFailed = 516
+)
+
+// IsErrorCode reports whether err is, or wraps, a server exception with the code, so callers can
+// branch on a condition such as IsErrorCode(err, CodeMemoryLimitExceeded) without matching messages.
+func IsErrorCode(err error, code int32) bool {
+	var exception *Exception
+	return errors.As(err, &exception) && exception.Code == code
+}
+
+// IsRetryable reports whether a read that failed with err may succeed when it's simply run again:
+// the connection broke, or the server failed it for a transient reason such as being too busy or
+// failing to reach a replica. Errors of the query itself, e.g. a syntax error, are not retryable.
+// Only idempotent queries should be retried, an insert may have been partially written.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isConnBrokenError(err) {
+		return true
+	}
+	var exception *Exception
+	if errors.As(err, &exception) {
+		switch exception.Code {
+		case CodeTooManySimultaneousQueries, CodeSocketTimeout, CodeNetworkError, CodeAllConnectionTriesFailed:
+			return true
+		}
+	}
+	return false
+}
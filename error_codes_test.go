@@ -0,0 +1,58 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"nil", nil, false},
+		{"too many simultaneous queries", &Exception{Code: CodeTooManySimultaneousQueries}, true},
+		{"network error", &Exception{Code: CodeNetworkError}, true},
+		{"wrapped", fmt.Errorf("query: %w", &Exception{Code: CodeSocketTimeout}), true},
+		{"broken connection", io.EOF, true},
+		{"syntax error", &Exception{Code: CodeSyntaxError}, false},
+		{"authentication failed", fmt.Errorf("handshake: %w", &Exception{Code: CodeAuthenticationFailed}), false},
+		{"client error", ErrBatchAlreadySent, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.retryable, IsRetryable(tc.err))
+		})
+	}
+}
+
+func TestIsErrorCode(t *testing.T) {
+	memory := fmt.Errorf("select: %w", &Exception{Code: CodeMemoryLimitExceeded, Message: "Memory limit exceeded"})
+	assert.True(t, IsErrorCode(memory, CodeMemoryLimitExceeded))
+	assert.False(t, IsErrorCode(memory, CodeTimeoutExceeded))
+	assert.True(t, IsErrorCode(&Exception{Code: CodeTimeoutExceeded}, CodeTimeoutExceeded))
+	assert.False(t, IsErrorCode(io.EOF, CodeNetworkError), "not a server exception")
+	assert.False(t, IsErrorCode(nil, CodeSyntaxError))
+}
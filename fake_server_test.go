@@ -0,0 +1,272 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ClickHouse/ch-go/compress"
+	chproto "github.com/ClickHouse/ch-go/proto"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+)
+
+// fakeServer speaks just enough of the native protocol to complete a handshake
+// and then replays a scripted sequence of server packets. Everything the client
+// sends after the handshake is recorded and can be inspected with sent.
+type fakeServer struct {
+	revision  uint64
+	timezone  string
+	exception *proto.Exception // returned instead of the server hello when set
+	script    chproto.Buffer
+	hangup    bool          // close the connection once the script is written instead of waiting for the client
+	ack       chan struct{} // the script from split on is held back until ack is closed, see awaiting
+	split     int
+
+	mu             sync.Mutex
+	clientName     string
+	clientRevision uint64 // the revision advertised in the client hello
+	database       string
+	username       string
+	password       string
+	quotaKey       string
+	received       bytes.Buffer
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		revision: ClientTCPProtocolVersion,
+		timezone: "UTC",
+	}
+}
+
+// dial satisfies Options.DialContext.
+func (s *fakeServer) dial(ctx context.Context, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return client, nil
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := chproto.NewReader(conn)
+	if err := s.readHello(reader); err != nil {
+		return
+	}
+	var hello chproto.Buffer
+	if s.exception != nil {
+		s.encodeException(&hello, s.exception)
+		conn.Write(hello.Buf)
+		return
+	}
+	hello.PutByte(proto.ServerHello)
+	hello.PutString("ClickHouse")
+	hello.PutUVarInt(24)
+	hello.PutUVarInt(3)
+	hello.PutUVarInt(s.revision)
+	if s.negotiated() >= proto.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE {
+		hello.PutString(s.timezone)
+	}
+	if s.negotiated() >= proto.DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME {
+		hello.PutString("fake")
+	}
+	if s.negotiated() >= proto.DBMS_MIN_REVISION_WITH_VERSION_PATCH {
+		hello.PutUVarInt(1)
+	}
+	if _, err := conn.Write(hello.Buf); err != nil {
+		return
+	}
+	if s.negotiated() >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_ADDENDUM {
+		quotaKey, err := reader.Str()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.quotaKey = quotaKey
+		s.mu.Unlock()
+	}
+	// the script answers the first client packet, which is recorded before replying
+	// so the client can't observe the reply ahead of its own request in sent
+	if !s.record(reader) {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for s.record(reader) {
+		}
+	}()
+	script := s.script.Buf
+	if s.ack != nil {
+		if _, err := conn.Write(script[:s.split]); err != nil {
+			return
+		}
+		<-s.ack
+		script = script[s.split:]
+	}
+	if _, err := conn.Write(script); err != nil || s.hangup {
+		return
+	}
+	// keep the connection open until the client is done with it
+	<-done
+}
+
+// record appends the next chunk the client sent to received.
+func (s *fakeServer) record(reader io.Reader) bool {
+	var buf [4096]byte
+	n, err := reader.Read(buf[:])
+	s.mu.Lock()
+	s.received.Write(buf[:n])
+	s.mu.Unlock()
+	return err == nil
+}
+
+// negotiated is the revision both sides use after the handshake, like a real server the fake
+// only sends what the client knows about.
+func (s *fakeServer) negotiated() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clientRevision != 0 {
+		return min(s.revision, s.clientRevision)
+	}
+	return min(s.revision, ClientTCPProtocolVersion)
+}
+
+func (s *fakeServer) readHello(reader *chproto.Reader) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = reader.ReadByte(); err != nil {
+		return err
+	}
+	if s.clientName, err = reader.Str(); err != nil {
+		return err
+	}
+	for i := 0; i < 2; i++ { // major, minor
+		if _, err = reader.UVarInt(); err != nil {
+			return err
+		}
+	}
+	if s.clientRevision, err = reader.UVarInt(); err != nil {
+		return err
+	}
+	if s.database, err = reader.Str(); err != nil {
+		return err
+	}
+	if s.username, err = reader.Str(); err != nil {
+		return err
+	}
+	s.password, err = reader.Str()
+	return err
+}
+
+// sent returns a copy of everything the client wrote after the handshake.
+func (s *fakeServer) sent() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.received.Bytes()...)
+}
+
+// awaiting holds the packets added to the script after it back until ack is closed, like a server
+// deferring its answer.
+func (s *fakeServer) awaiting(ack chan struct{}) *fakeServer {
+	s.ack, s.split = ack, len(s.script.Buf)
+	return s
+}
+
+func (s *fakeServer) pong() *fakeServer {
+	s.script.PutByte(proto.ServerPong)
+	return s
+}
+
+func (s *fakeServer) endOfStream() *fakeServer {
+	s.script.PutByte(proto.ServerEndOfStream)
+	return s
+}
+
+func (s *fakeServer) data(block *proto.Block) *fakeServer {
+	return s.block(proto.ServerData, block)
+}
+
+func (s *fakeServer) block(packet byte, block *proto.Block) *fakeServer {
+	s.script.PutByte(packet)
+	s.script.PutString("")
+	if err := block.Encode(&s.script, s.negotiated()); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// compressedData writes a data packet whose block is framed the way the server compresses
+// blocks for clients that asked for compression.
+func (s *fakeServer) compressedData(block *proto.Block, method compress.Method) *fakeServer {
+	var data chproto.Buffer
+	if err := block.Encode(&data, s.negotiated()); err != nil {
+		panic(err)
+	}
+	w := compress.NewWriter()
+	if err := w.Compress(method, data.Buf); err != nil {
+		panic(err)
+	}
+	s.script.PutByte(proto.ServerData)
+	s.script.PutString("")
+	s.script.PutRaw(w.Data)
+	return s
+}
+
+func (s *fakeServer) progress(rows, wroteRows uint64) *fakeServer {
+	s.script.PutByte(proto.ServerProgress)
+	s.script.PutUVarInt(rows)
+	s.script.PutUVarInt(0) // bytes
+	s.script.PutUVarInt(0) // total rows
+	if s.negotiated() >= proto.DBMS_MIN_REVISION_WITH_CLIENT_WRITE_INFO {
+		s.script.PutUVarInt(wroteRows)
+		s.script.PutUVarInt(0) // wrote bytes
+	}
+	if s.negotiated() >= proto.DBMS_MIN_PROTOCOL_VERSION_WITH_SERVER_QUERY_TIME_IN_PROGRES {
+		s.script.PutUVarInt(0) // elapsed
+	}
+	return s
+}
+
+func (s *fakeServer) profileInfo(rows uint64) *fakeServer {
+	s.script.PutByte(proto.ServerProfileInfo)
+	s.script.PutUVarInt(rows)
+	s.script.PutUVarInt(1) // blocks
+	s.script.PutUVarInt(0) // bytes
+	s.script.PutBool(false)
+	s.script.PutUVarInt(0) // rows before limit
+	s.script.PutBool(false)
+	return s
+}
+
+func (s *fakeServer) raise(code int32, message string) *fakeServer {
+	s.encodeException(&s.script, &proto.Exception{Code: code, Name: "DB::Exception", Message: message})
+	return s
+}
+
+func (s *fakeServer) encodeException(buffer *chproto.Buffer, e *proto.Exception) {
+	buffer.PutByte(proto.ServerException)
+	buffer.PutInt32(e.Code)
+	buffer.PutString(e.Name)
+	buffer.PutString(e.Message)
+	buffer.PutString(e.StackTrace)
+	buffer.PutBool(false)
+}
@@ -0,0 +1,121 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	minHostBackoff = time.Second
+	maxHostBackoff = time.Minute
+)
+
+// hostHealth remembers the hosts that failed to connect. With ConnOpenHealthy they are tried
+// after the other hosts until their backoff, doubled with each consecutive failure, has passed.
+type hostHealth struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	hosts map[string]*hostBackoff
+}
+
+type hostBackoff struct {
+	failures int
+	until    time.Time
+}
+
+func newHostHealth() *hostHealth {
+	return &hostHealth{
+		now:   time.Now,
+		hosts: make(map[string]*hostBackoff),
+	}
+}
+
+func (h *hostHealth) failed(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	host, ok := h.hosts[addr]
+	if !ok {
+		host = &hostBackoff{}
+		h.hosts[addr] = host
+	}
+	backoff := maxHostBackoff
+	if host.failures < 6 { // 1s << 6 is past the cap
+		backoff = min(minHostBackoff<<host.failures, maxHostBackoff)
+	}
+	host.failures++
+	host.until = h.now().Add(backoff)
+}
+
+func (h *hostHealth) succeeded(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.hosts, addr)
+}
+
+func (h *hostHealth) available(addr string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	host, ok := h.hosts[addr]
+	return !ok || !h.now().Before(host.until)
+}
+
+// hostOrder returns the order the hosts of Addr are dialed in for the connection connID.
+func (o *Options) hostOrder(connID int) []int {
+	order := make([]int, 0, len(o.Addr))
+	for i := range o.Addr {
+		switch o.ConnOpenStrategy {
+		case ConnOpenRoundRobin, ConnOpenHealthy:
+			order = append(order, (connID+i)%len(o.Addr))
+		default:
+			order = append(order, i)
+		}
+	}
+	if o.ConnOpenStrategy != ConnOpenHealthy || o.health == nil {
+		return order
+	}
+	// hosts in backoff go last, they are still tried when every other host is down
+	healthy := order[:0:0]
+	var backoff []int
+	for _, num := range order {
+		if o.health.available(o.Addr[num]) {
+			healthy = append(healthy, num)
+		} else {
+			backoff = append(backoff, num)
+		}
+	}
+	return append(healthy, backoff...)
+}
+
+// dialed records the outcome of dialing addr for ConnOpenHealthy. A dial cut short by ctx says
+// nothing about the host, it isn't held against it.
+func (o *Options) dialed(ctx context.Context, addr string, err error) {
+	if o.health == nil {
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			o.health.failed(addr)
+		}
+		return
+	}
+	o.health.succeeded(addr)
+}
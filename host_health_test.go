@@ -0,0 +1,118 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostHealthBackoff(t *testing.T) {
+	now := time.Now()
+	health := newHostHealth()
+	health.now = func() time.Time { return now }
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	for i, backoff := range expected {
+		health.failed("a")
+		assert.False(t, health.available("a"), "failure %d", i+1)
+		now = now.Add(backoff - time.Nanosecond)
+		assert.False(t, health.available("a"), "failure %d", i+1)
+		now = now.Add(time.Nanosecond)
+		assert.True(t, health.available("a"), "failure %d", i+1)
+	}
+
+	health.succeeded("a")
+	health.failed("a")
+	now = now.Add(time.Second)
+	assert.True(t, health.available("a"), "a success resets the backoff")
+	assert.True(t, health.available("b"))
+}
+
+func TestDialStrategyHealthy(t *testing.T) {
+	var (
+		now     = time.Now()
+		opt     = (&Options{Addr: []string{"a", "b"}, ConnOpenStrategy: ConnOpenHealthy}).setDefaults()
+		down    = true
+		dialed  []string
+		errDown = errors.New("connection refused")
+		dial    = func(ctx context.Context, addr string, opt *Options) (DialResult, error) {
+			dialed = append(dialed, addr)
+			if addr == "a" && down {
+				return DialResult{}, errDown
+			}
+			return DialResult{}, nil
+		}
+	)
+	opt.health.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		recover bool
+		dialed  []string
+	}{
+		{"a fails over to b", 0, false, []string{"a", "b"}},
+		{"a is skipped while backing off", 0, false, []string{"b"}},
+		{"a is retried after its backoff", time.Second, false, []string{"a", "b"}},
+		{"the backoff doubled", time.Second, false, []string{"b"}},
+		{"a recovers", time.Second, true, []string{"a"}},
+		{"a is picked again", 0, false, []string{"a"}},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		if step.recover {
+			down = false
+		}
+		dialed = nil
+		// even connection ids start the round robin at a
+		_, err := DefaultDialStrategy(context.Background(), 2*i, opt, dial)
+		require.NoError(t, err, step.name)
+		assert.Equal(t, step.dialed, dialed, step.name)
+	}
+
+	t.Run("every host down", func(t *testing.T) {
+		opt := (&Options{Addr: []string{"a", "b"}, ConnOpenStrategy: ConnOpenHealthy}).setDefaults()
+		dial := func(ctx context.Context, addr string, opt *Options) (DialResult, error) {
+			return DialResult{}, errDown
+		}
+		for i := 0; i < 3; i++ {
+			_, err := DefaultDialStrategy(context.Background(), i, opt, dial)
+			assert.ErrorIs(t, err, errDown)
+		}
+		assert.Len(t, opt.hostOrder(0), 2, "hosts in backoff are still tried")
+	})
+}
+
+func TestHostHealthCancelledDial(t *testing.T) {
+	opt := (&Options{Addr: []string{"a"}, ConnOpenStrategy: ConnOpenHealthy}).setDefaults()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opt.dialed(ctx, "a", errors.New("dial tcp: operation was canceled"))
+	opt.dialed(context.Background(), "a", &dialError{err: context.DeadlineExceeded})
+	assert.True(t, opt.health.available("a"), "the caller gave up, the host didn't fail")
+
+	opt.dialed(context.Background(), "a", errors.New("connection refused"))
+	assert.False(t, opt.health.available("a"))
+}
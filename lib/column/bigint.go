@@ -84,14 +84,18 @@ func (col *BigInt) Append(v any) (nulls []uint8, err error) {
 	case []big.Int:
 		nulls = make([]uint8, len(v))
 		for i := range v {
-			col.append(&v[i])
+			if err := col.append(&v[i]); err != nil {
+				return nil, err
+			}
 		}
 	case []*big.Int:
 		nulls = make([]uint8, len(v))
 		for i := range v {
 			switch {
-			case v != nil:
-				col.append(v[i])
+			case v[i] != nil:
+				if err := col.append(v[i]); err != nil {
+					return nil, err
+				}
 			default:
 				nulls[i] = 1
 				col.append(big.NewInt(0))
@@ -122,11 +126,11 @@ func (col *BigInt) Append(v any) (nulls []uint8, err error) {
 func (col *BigInt) AppendRow(v any) error {
 	switch v := v.(type) {
 	case big.Int:
-		col.append(&v)
+		return col.append(&v)
 	case *big.Int:
 		switch {
 		case v != nil:
-			col.append(v)
+			return col.append(v)
 		default:
 			col.append(big.NewInt(0))
 		}
@@ -193,7 +197,15 @@ func (col *BigInt) row(i int) *big.Int {
 	return big.NewInt(0)
 }
 
-func (col *BigInt) append(v *big.Int) {
+// append adds v to the column, a value the width and signedness of the type can't hold is refused
+// as it would otherwise be truncated.
+func (col *BigInt) append(v *big.Int) error {
+	if !col.fits(v) {
+		return &Error{
+			ColumnType: string(col.chType),
+			Err:        fmt.Errorf("value %s out of range", v),
+		}
+	}
 	dest := make([]byte, col.size)
 	bigIntToRaw(dest, new(big.Int).Set(v))
 	switch v := col.col.(type) {
@@ -230,6 +242,20 @@ func (col *BigInt) append(v *big.Int) {
 			},
 		})
 	}
+	return nil
+}
+
+func (col *BigInt) fits(v *big.Int) bool {
+	bits := col.size * 8
+	switch {
+	case !col.signed:
+		return v.Sign() >= 0 && v.BitLen() <= bits
+	case v.Sign() < 0:
+		// -2^(bits-1) is the smallest, i.e. -v-1 takes at most bits-1 bits
+		return new(big.Int).Not(v).BitLen() < bits
+	default:
+		return v.BitLen() < bits
+	}
 }
 
 func bigIntToRaw(dest []byte, v *big.Int) {
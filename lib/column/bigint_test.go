@@ -0,0 +1,120 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigInt(t *testing.T) {
+	pow := func(n uint) *big.Int { return new(big.Int).Lsh(big.NewInt(1), n) }
+	add := func(a *big.Int, b int64) *big.Int { return new(big.Int).Add(a, big.NewInt(b)) }
+	neg := func(a *big.Int) *big.Int { return new(big.Int).Neg(a) }
+	beyondUint64 := add(pow(64), 12345)
+
+	testCases := []struct {
+		chType     Type
+		values     []*big.Int
+		outOfRange []*big.Int
+	}{
+		{"Int128", []*big.Int{beyondUint64, neg(beyondUint64), add(pow(127), -1), neg(pow(127)), big.NewInt(-1)}, []*big.Int{pow(127), add(neg(pow(127)), -1)}},
+		{"UInt128", []*big.Int{beyondUint64, add(pow(128), -1), big.NewInt(0)}, []*big.Int{pow(128), big.NewInt(-1)}},
+		{"Int256", []*big.Int{beyondUint64, neg(add(pow(200), 7)), add(pow(255), -1), neg(pow(255))}, []*big.Int{pow(255), add(neg(pow(255)), -1)}},
+		{"UInt256", []*big.Int{beyondUint64, add(pow(256), -1)}, []*big.Int{pow(256), neg(beyondUint64)}},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.chType), func(t *testing.T) {
+			col, err := tc.chType.Column("n", nil)
+			require.NoError(t, err)
+			_, err = col.Append(tc.values)
+			require.NoError(t, err)
+			require.NoError(t, col.AppendRow(*tc.values[0]))
+
+			decoded := roundTrip(t, col)
+			require.Equal(t, len(tc.values)+1, decoded.Rows())
+			for i, expected := range append(tc.values, tc.values[0]) {
+				var value big.Int
+				require.NoError(t, decoded.ScanRow(&value, i))
+				assert.Zero(t, expected.Cmp(&value), "%s != %s", expected, &value)
+			}
+
+			for _, v := range tc.outOfRange {
+				assert.ErrorContains(t, col.AppendRow(v), "out of range", v.String())
+				_, err := col.Append([]*big.Int{v})
+				assert.ErrorContains(t, err, "out of range", v.String())
+			}
+		})
+	}
+
+	t.Run("nil in a slice", func(t *testing.T) {
+		col, err := Type("Int128").Column("n", nil)
+		require.NoError(t, err)
+		nulls, err := col.Append([]*big.Int{big.NewInt(1), nil})
+		require.NoError(t, err)
+		assert.Equal(t, []uint8{0, 1}, nulls)
+	})
+}
+
+func TestBigIntWire(t *testing.T) {
+	pow := func(n uint) *big.Int { return new(big.Int).Lsh(big.NewInt(1), n) }
+	// little endian two's complement, as ClickHouse lays the values out
+	wire := func(size int, fill byte, last byte, set ...int) []byte {
+		b := bytes.Repeat([]byte{fill}, size)
+		b[size-1] = last
+		for _, i := range set {
+			b[i] = 1
+		}
+		return b
+	}
+	testCases := []struct {
+		chType   Type
+		value    *big.Int
+		expected []byte
+	}{
+		{"Int128", big.NewInt(-1), wire(16, 0xff, 0xff)},
+		{"Int128", new(big.Int).Neg(pow(127)), wire(16, 0, 0x80)},
+		{"Int128", new(big.Int).Sub(pow(127), big.NewInt(1)), wire(16, 0xff, 0x7f)},
+		{"UInt128", new(big.Int).Sub(pow(128), big.NewInt(1)), wire(16, 0xff, 0xff)},
+		{"UInt128", pow(64), wire(16, 0, 0, 8)},
+		{"Int256", new(big.Int).Neg(pow(255)), wire(32, 0, 0x80)},
+		{"Int256", new(big.Int).Sub(pow(255), big.NewInt(1)), wire(32, 0xff, 0x7f)},
+		{"UInt256", new(big.Int).Sub(pow(256), big.NewInt(1)), wire(32, 0xff, 0xff)},
+		{"UInt256", big.NewInt(1), wire(32, 0, 0, 0)},
+	}
+	for _, tc := range testCases {
+		col, err := tc.chType.Column("n", nil)
+		require.NoError(t, err)
+		require.NoError(t, col.AppendRow(tc.value))
+		var buf proto.Buffer
+		col.Encode(&buf)
+		assert.Equal(t, tc.expected, buf.Buf, "%s %s", tc.chType, tc.value)
+
+		decoded, err := tc.chType.Column("n", nil)
+		require.NoError(t, err)
+		require.NoError(t, decoded.Decode(proto.NewReader(bytes.NewReader(tc.expected)), 1))
+		var value *big.Int
+		require.NoError(t, decoded.ScanRow(&value, 0))
+		assert.Zero(t, tc.value.Cmp(value), "%s %s != %s", tc.chType, tc.value, value)
+	}
+}
@@ -0,0 +1,94 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoolMapping(t *testing.T) {
+	yes, no := true, false
+	for _, chType := range []Type{"Bool", "UInt8", "Int8"} {
+		t.Run(string(chType), func(t *testing.T) {
+			col, err := chType.Column("flag", nil)
+			require.NoError(t, err)
+
+			for _, v := range []any{true, false, &yes, &no, (*bool)(nil)} {
+				require.NoError(t, col.AppendRow(v))
+			}
+			_, err = col.Append([]bool{true, false})
+			require.NoError(t, err)
+			nulls, err := col.Append([]*bool{&yes, nil})
+			require.NoError(t, err)
+			assert.Equal(t, []uint8{0, 1}, nulls)
+
+			decoded := roundTrip(t, col)
+			expected := []bool{true, false, true, false, false, true, false, true, false}
+			require.Equal(t, len(expected), decoded.Rows())
+			for i, want := range expected {
+				var (
+					value    bool
+					ptr      *bool
+					nullable sql.NullBool
+				)
+				require.NoError(t, decoded.ScanRow(&value, i))
+				require.NoError(t, decoded.ScanRow(&ptr, i))
+				require.NoError(t, decoded.ScanRow(&nullable, i))
+				assert.Equal(t, want, value, "row %d", i)
+				require.NotNil(t, ptr)
+				assert.Equal(t, want, *ptr, "row %d", i)
+				assert.Equal(t, sql.NullBool{Bool: want, Valid: true}, nullable, "row %d", i)
+			}
+		})
+	}
+
+	t.Run("non-zero UInt8 scans as true", func(t *testing.T) {
+		col, err := Type("UInt8").Column("flag", nil)
+		require.NoError(t, err)
+		require.NoError(t, col.AppendRow(uint8(7)))
+		var value bool
+		require.NoError(t, roundTrip(t, col).ScanRow(&value, 0))
+		assert.True(t, value)
+	})
+}
+
+func TestBoolNullBool(t *testing.T) {
+	col, err := Type("Nullable(Bool)").Column("flag", nil)
+	require.NoError(t, err)
+
+	nulls, err := col.Append([]sql.NullBool{{Bool: true, Valid: true}, {}})
+	require.NoError(t, err)
+	assert.Equal(t, []uint8{0, 1}, nulls)
+	nulls, err = col.Append([]*sql.NullBool{{Bool: false, Valid: true}, nil})
+	require.NoError(t, err)
+	assert.Equal(t, []uint8{0, 1}, nulls)
+	require.NoError(t, col.AppendRow((*sql.NullBool)(nil)))
+
+	decoded := roundTrip(t, col)
+	expected := []sql.NullBool{{Bool: true, Valid: true}, {}, {Bool: false, Valid: true}, {}, {}}
+	require.Equal(t, len(expected), decoded.Rows())
+	for i, want := range expected {
+		var value sql.NullBool
+		require.NoError(t, decoded.ScanRow(&value, i))
+		assert.Equal(t, want, value, "row %d", i)
+	}
+}
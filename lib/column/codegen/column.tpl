@@ -120,7 +120,7 @@ func (t Type) Column(name string, tz *time.Location) (Interface, error) {
 	case "Point":
 		return &Point{name: name}, nil
 	case "String":
-		return &String{name: name}, nil
+		return &String{name: name, col: colStrProvider()}, nil
 	case "Object('json')":
 	    return &JSONObject{name: name, root: true, tz: tz}, nil
 	}
@@ -233,12 +233,10 @@ func (col *{{ .ChType }}) ScanRow(dest any, row int) error {
 	{{- end }}
     {{- if or (eq .ChType "Int8") (eq .ChType "UInt8")  }}
 	case *bool:
-		switch value {
-		case 0:
-			*d = false
-		default:
-			*d = true
-		}
+		*d = value != 0
+	case **bool:
+		*d = new(bool)
+		**d = value != 0
     {{- end }}
 	default:
 		if scan, ok := dest.(sql.Scanner); ok {
@@ -295,11 +293,11 @@ func (col *{{ .ChType }}) Append(v any) (nulls []uint8,err error) {
             col.AppendRow(v[i])
         }
 	{{- end }}
-	{{- if eq .ChType "Int8" }}
+	{{- if or (eq .ChType "Int8") (eq .ChType "UInt8") }}
 	case []bool:
 		nulls = make([]uint8, len(v))
 		for i := range v {
-			val := int8(0)
+			val := {{ .GoType }}(0)
 			if v[i] {
 				val = 1
 			}
@@ -308,8 +306,11 @@ func (col *{{ .ChType }}) Append(v any) (nulls []uint8,err error) {
 	case []*bool:
 		nulls = make([]uint8, len(v))
 		for i := range v {
-			val := int8(0)
-			if *v[i] {
+			val := {{ .GoType }}(0)
+			switch {
+			case v[i] == nil:
+				nulls[i] = 1
+			case *v[i]:
 				val = 1
 			}
 			col.col.Append(val)
@@ -352,14 +353,6 @@ func (col *{{ .ChType }}) AppendRow(v any) error {
         }
 	case nil:
 		col.col.Append(0)
-	{{- if eq .ChType "UInt8" }}
-	case bool:
-		var t uint8
-		if v {
-			t = 1
-		}
-		col.col.Append(t)
-	{{- end }}
     {{- if or (eq .ChType "Int64") (eq .ChType "Int32") (eq .ChType "Int16") (eq .ChType "Float64") }}
     case sql.Null{{ .ChType }}:
         switch v.Valid {
@@ -382,16 +375,16 @@ func (col *{{ .ChType }}) AppendRow(v any) error {
     case *time.Duration:
         col.col.Append(int64(*v))
 	{{- end }}
-	{{- if eq .ChType "Int8" }}
+	{{- if or (eq .ChType "Int8") (eq .ChType "UInt8") }}
     case bool:
-        val := int8(0)
+        val := {{ .GoType }}(0)
         if v {
             val = 1
         }
         col.col.Append(val)
     case *bool:
-        val := int8(0)
-        if *v {
+        val := {{ .GoType }}(0)
+        if v != nil && *v {
             val = 1
         }
         col.col.Append(val)
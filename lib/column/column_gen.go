@@ -597,12 +597,10 @@ func (col *Int8) ScanRow(dest any, row int) error {
 		*d = new(int8)
 		**d = value
 	case *bool:
-		switch value {
-		case 0:
-			*d = false
-		default:
-			*d = true
-		}
+		*d = value != 0
+	case **bool:
+		*d = new(bool)
+		**d = value != 0
 	default:
 		if scan, ok := dest.(sql.Scanner); ok {
 			return scan.Scan(value)
@@ -656,7 +654,10 @@ func (col *Int8) Append(v any) (nulls []uint8, err error) {
 		nulls = make([]uint8, len(v))
 		for i := range v {
 			val := int8(0)
-			if *v[i] {
+			switch {
+			case v[i] == nil:
+				nulls[i] = 1
+			case *v[i]:
 				val = 1
 			}
 			col.col.Append(val)
@@ -706,7 +707,7 @@ func (col *Int8) AppendRow(v any) error {
 		col.col.Append(val)
 	case *bool:
 		val := int8(0)
-		if *v {
+		if v != nil && *v {
 			val = 1
 		}
 		col.col.Append(val)
@@ -1291,12 +1292,10 @@ func (col *UInt8) ScanRow(dest any, row int) error {
 		*d = new(uint8)
 		**d = value
 	case *bool:
-		switch value {
-		case 0:
-			*d = false
-		default:
-			*d = true
-		}
+		*d = value != 0
+	case **bool:
+		*d = new(bool)
+		**d = value != 0
 	default:
 		if scan, ok := dest.(sql.Scanner); ok {
 			return scan.Scan(value)
@@ -1337,6 +1336,27 @@ func (col *UInt8) Append(v any) (nulls []uint8, err error) {
 				nulls[i] = 1
 			}
 		}
+	case []bool:
+		nulls = make([]uint8, len(v))
+		for i := range v {
+			val := uint8(0)
+			if v[i] {
+				val = 1
+			}
+			col.col.Append(val)
+		}
+	case []*bool:
+		nulls = make([]uint8, len(v))
+		for i := range v {
+			val := uint8(0)
+			switch {
+			case v[i] == nil:
+				nulls[i] = 1
+			case *v[i]:
+				val = 1
+			}
+			col.col.Append(val)
+		}
 	default:
 
 		if valuer, ok := v.(driver.Valuer); ok {
@@ -1375,11 +1395,17 @@ func (col *UInt8) AppendRow(v any) error {
 	case nil:
 		col.col.Append(0)
 	case bool:
-		var t uint8
+		val := uint8(0)
 		if v {
-			t = 1
+			val = 1
 		}
-		col.col.Append(t)
+		col.col.Append(val)
+	case *bool:
+		val := uint8(0)
+		if v != nil && *v {
+			val = 1
+		}
+		col.col.Append(val)
 	default:
 
 		if valuer, ok := v.(driver.Valuer); ok {
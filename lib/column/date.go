@@ -101,20 +101,20 @@ func (col *Date) Append(v any) (nulls []uint8, err error) {
 	switch v := v.(type) {
 	case []time.Time:
 		for _, t := range v {
-			if err := dateOverflow(minDate, maxDate, t, defaultDateFormatNoZone); err != nil {
+			if err := dateOverflow(minDate, maxDate, calendarDate(t, col.location), defaultDateFormatNoZone); err != nil {
 				return nil, err
 			}
-			col.col.Append(t)
+			col.col.Append(calendarDate(t, col.location))
 		}
 	case []*time.Time:
 		nulls = make([]uint8, len(v))
 		for i, v := range v {
 			switch {
 			case v != nil:
-				if err := dateOverflow(minDate, maxDate, *v, defaultDateFormatNoZone); err != nil {
+				if err := dateOverflow(minDate, maxDate, calendarDate(*v, col.location), defaultDateFormatNoZone); err != nil {
 					return nil, err
 				}
-				col.col.Append(*v)
+				col.col.Append(calendarDate(*v, col.location))
 			default:
 				nulls[i] = 1
 				col.col.Append(time.Time{})
@@ -140,7 +140,7 @@ func (col *Date) Append(v any) (nulls []uint8, err error) {
 			if err != nil {
 				return nil, err
 			}
-			col.col.Append(value)
+			col.col.Append(calendarDate(value, col.location))
 		}
 	case []*string:
 		nulls = make([]uint8, len(v))
@@ -153,7 +153,7 @@ func (col *Date) Append(v any) (nulls []uint8, err error) {
 				if err != nil {
 					return nil, err
 				}
-				col.col.Append(value)
+				col.col.Append(calendarDate(value, col.location))
 			}
 		}
 	default:
@@ -181,31 +181,31 @@ func (col *Date) Append(v any) (nulls []uint8, err error) {
 func (col *Date) AppendRow(v any) error {
 	switch v := v.(type) {
 	case time.Time:
-		if err := dateOverflow(minDate, maxDate, v, defaultDateFormatNoZone); err != nil {
+		if err := dateOverflow(minDate, maxDate, calendarDate(v, col.location), defaultDateFormatNoZone); err != nil {
 			return err
 		}
-		col.col.Append(v)
+		col.col.Append(calendarDate(v, col.location))
 	case *time.Time:
 		switch {
 		case v != nil:
-			if err := dateOverflow(minDate, maxDate, *v, defaultDateFormatNoZone); err != nil {
+			if err := dateOverflow(minDate, maxDate, calendarDate(*v, col.location), defaultDateFormatNoZone); err != nil {
 				return err
 			}
-			col.col.Append(*v)
+			col.col.Append(calendarDate(*v, col.location))
 		default:
 			col.col.Append(time.Time{})
 		}
 	case sql.NullTime:
 		switch v.Valid {
 		case true:
-			col.col.Append(v.Time)
+			col.col.Append(calendarDate(v.Time, col.location))
 		default:
 			col.col.Append(time.Time{})
 		}
 	case *sql.NullTime:
 		switch v.Valid {
 		case true:
-			col.col.Append(v.Time)
+			col.col.Append(calendarDate(v.Time, col.location))
 		default:
 			col.col.Append(time.Time{})
 		}
@@ -216,7 +216,7 @@ func (col *Date) AppendRow(v any) error {
 		if err != nil {
 			return err
 		}
-		col.col.Append(datetime)
+		col.col.Append(calendarDate(datetime, col.location))
 	case *string:
 		if v == nil || *v == "" {
 			col.col.Append(time.Time{})
@@ -225,7 +225,7 @@ func (col *Date) AppendRow(v any) error {
 			if err != nil {
 				return err
 			}
-			col.col.Append(datetime)
+			col.col.Append(calendarDate(datetime, col.location))
 		}
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
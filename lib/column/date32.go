@@ -92,20 +92,20 @@ func (col *Date32) Append(v any) (nulls []uint8, err error) {
 	switch v := v.(type) {
 	case []time.Time:
 		for _, t := range v {
-			if err := dateOverflow(minDate32, maxDate32, t, "2006-01-02"); err != nil {
+			if err := dateOverflow(minDate32, maxDate32, calendarDate(t, col.location), "2006-01-02"); err != nil {
 				return nil, err
 			}
-			col.col.Append(t)
+			col.col.Append(calendarDate(t, col.location))
 		}
 	case []*time.Time:
 		nulls = make([]uint8, len(v))
 		for i, v := range v {
 			switch {
 			case v != nil:
-				if err := dateOverflow(minDate32, maxDate32, *v, "2006-01-02"); err != nil {
+				if err := dateOverflow(minDate32, maxDate32, calendarDate(*v, col.location), "2006-01-02"); err != nil {
 					return nil, err
 				}
-				col.col.Append(*v)
+				col.col.Append(calendarDate(*v, col.location))
 			default:
 				nulls[i] = 1
 				col.col.Append(time.Time{})
@@ -131,7 +131,7 @@ func (col *Date32) Append(v any) (nulls []uint8, err error) {
 			if err != nil {
 				return nil, err
 			}
-			col.col.Append(value)
+			col.col.Append(calendarDate(value, col.location))
 		}
 	case []*string:
 		nulls = make([]uint8, len(v))
@@ -144,7 +144,7 @@ func (col *Date32) Append(v any) (nulls []uint8, err error) {
 				if err != nil {
 					return nil, err
 				}
-				col.col.Append(value)
+				col.col.Append(calendarDate(value, col.location))
 			}
 		}
 	default:
@@ -172,31 +172,31 @@ func (col *Date32) Append(v any) (nulls []uint8, err error) {
 func (col *Date32) AppendRow(v any) error {
 	switch v := v.(type) {
 	case time.Time:
-		if err := dateOverflow(minDate32, maxDate32, v, "2006-01-02"); err != nil {
+		if err := dateOverflow(minDate32, maxDate32, calendarDate(v, col.location), "2006-01-02"); err != nil {
 			return err
 		}
-		col.col.Append(v)
+		col.col.Append(calendarDate(v, col.location))
 	case *time.Time:
 		switch {
 		case v != nil:
-			if err := dateOverflow(minDate32, maxDate32, *v, "2006-01-02"); err != nil {
+			if err := dateOverflow(minDate32, maxDate32, calendarDate(*v, col.location), "2006-01-02"); err != nil {
 				return err
 			}
-			col.col.Append(*v)
+			col.col.Append(calendarDate(*v, col.location))
 		default:
 			col.col.Append(time.Time{})
 		}
 	case sql.NullTime:
 		switch v.Valid {
 		case true:
-			col.col.Append(v.Time)
+			col.col.Append(calendarDate(v.Time, col.location))
 		default:
 			col.col.Append(time.Time{})
 		}
 	case *sql.NullTime:
 		switch v.Valid {
 		case true:
-			col.col.Append(v.Time)
+			col.col.Append(calendarDate(v.Time, col.location))
 		default:
 			col.col.Append(time.Time{})
 		}
@@ -207,7 +207,7 @@ func (col *Date32) AppendRow(v any) error {
 		if err != nil {
 			return err
 		}
-		col.col.Append(value)
+		col.col.Append(calendarDate(value, col.location))
 	case *string:
 		if v == nil || *v == "" {
 			col.col.Append(time.Time{})
@@ -216,7 +216,7 @@ func (col *Date32) AppendRow(v any) error {
 			if err != nil {
 				return err
 			}
-			col.col.Append(value)
+			col.col.Append(calendarDate(value, col.location))
 		}
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
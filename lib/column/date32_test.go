@@ -0,0 +1,66 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate32(t *testing.T) {
+	values := []time.Time{
+		time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1925, 6, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2200, 2, 28, 0, 0, 0, 0, time.UTC),
+		time.Date(2299, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	col, err := Type("Date32").Column("d", nil)
+	require.NoError(t, err)
+	assert.IsType(t, &Date32{}, col, "not mistaken for Date")
+	_, err = col.Append(values)
+	require.NoError(t, err)
+	require.NoError(t, col.AppendRow("1950-03-04"))
+
+	decoded := roundTrip(t, col)
+	require.Equal(t, len(values)+1, decoded.Rows())
+	for i, expected := range append(values, time.Date(1950, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		var value time.Time
+		require.NoError(t, decoded.ScanRow(&value, i))
+		assert.True(t, expected.Equal(value), "%s != %s", expected, value)
+	}
+
+	for _, outOfRange := range []time.Time{
+		time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		var overflow *DateOverflowError
+		assert.ErrorAs(t, col.AppendRow(outOfRange), &overflow, outOfRange.String())
+	}
+
+	t.Run("Date keeps its narrower range", func(t *testing.T) {
+		col, err := Type("Date").Column("d", nil)
+		require.NoError(t, err)
+		assert.IsType(t, &Date{}, col)
+		var overflow *DateOverflowError
+		assert.ErrorAs(t, col.AppendRow(time.Date(1925, 6, 15, 0, 0, 0, 0, time.UTC)), &overflow)
+	})
+}
@@ -24,6 +24,21 @@ import (
 
 const secInDay = 24 * 60 * 60
 
+// calendarDate returns the day of t in location, the server time zone or the one of the column, as
+// midnight UTC, the value a Date or Date32 column stores. It's the day the server takes for the same
+// instant inserted into a DateTime and converted to a Date. Dropping the time of day first keeps a
+// late evening value from being rolled into the next day by the offset of its location, and a value
+// before 1970 from being rounded to the next day. Without a location the one of t is used.
+func calendarDate(t time.Time, location *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	if location != nil {
+		t = t.In(location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
 func dateOverflow(min, max, v time.Time, format string) error {
 	if !v.IsZero() && (v.Before(min) || v.After(max)) {
 		return &DateOverflowError{
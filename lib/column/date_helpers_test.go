@@ -1,8 +1,12 @@
 package column
 
 import (
+	"fmt"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDateOverflow(t *testing.T) {
@@ -51,3 +55,72 @@ func TestDateOverflow(t *testing.T) {
 		})
 	}
 }
+
+func TestDateTruncation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		chType   Type
+		value    time.Time
+		expected string
+	}{
+		{"late evening west of UTC", "Date", time.Date(2024, 3, 1, 23, 30, 0, 0, newYork), "2024-03-01"},
+		{"early morning east of UTC", "Date", time.Date(2024, 3, 2, 0, 30, 0, 0, moscow), "2024-03-02"},
+		{"late evening Date32", "Date32", time.Date(2024, 3, 1, 23, 30, 0, 0, newYork), "2024-03-01"},
+		{"afternoon before 1970", "Date32", time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), "1969-07-20"},
+		{"first day early east of UTC", "Date", time.Date(1970, 1, 1, 1, 0, 0, 0, moscow), "1970-01-01"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			col, err := tc.chType.Column("d", nil)
+			require.NoError(t, err)
+			require.NoError(t, col.AppendRow(tc.value))
+			_, err = col.Append([]time.Time{tc.value})
+			require.NoError(t, err)
+
+			decoded := roundTrip(t, col)
+			for i := 0; i < decoded.Rows(); i++ {
+				var date time.Time
+				require.NoError(t, decoded.ScanRow(&date, i))
+				assert.Equal(t, tc.expected, date.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestDateTruncationInColumnLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// 23:30 on March 1st in New York is already March 2nd in UTC
+	value := time.Date(2024, 3, 1, 23, 30, 0, 0, newYork)
+
+	for _, tc := range []struct {
+		chType   Type
+		location *time.Location
+		expected string
+	}{
+		{"Date", time.UTC, "2024-03-02"},
+		{"Date32", time.UTC, "2024-03-02"},
+		{"Date", newYork, "2024-03-01"},
+		{"Date32", newYork, "2024-03-01"},
+	} {
+		t.Run(fmt.Sprintf("%s in %s", tc.chType, tc.location), func(t *testing.T) {
+			col, err := tc.chType.Column("d", tc.location)
+			require.NoError(t, err)
+			require.NoError(t, col.AppendRow(value))
+			_, err = col.Append([]*time.Time{&value})
+			require.NoError(t, err)
+
+			decoded := roundTrip(t, col)
+			for i := 0; i < decoded.Rows(); i++ {
+				var date time.Time
+				require.NoError(t, decoded.ScanRow(&date, i))
+				assert.Equal(t, tc.expected, date.Format("2006-01-02"), "the day of the server time zone")
+			}
+		})
+	}
+}
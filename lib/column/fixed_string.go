@@ -94,8 +94,8 @@ func (col *FixedString) ScanRow(dest any, row int) error {
 					Hint: fmt.Sprintf("invalid size %d, expect %d", size, col.col.Size),
 				}
 			}
-			rv := reflect.ValueOf(dest).Elem()
-			reflect.Copy(rv, reflect.ValueOf(col.row(row)))
+			// copied straight out of the column buffer, the array is the caller's own storage
+			reflect.Copy(reflect.ValueOf(dest).Elem(), reflect.ValueOf(col.col.Row(row)))
 			return nil
 		}
 
@@ -275,8 +275,9 @@ func (col *FixedString) row(i int) string {
 	return string(v)
 }
 
+// rowBytes returns a copy of row i, the column buffer is reused once the block has been read.
 func (col *FixedString) rowBytes(i int) []byte {
-	return col.col.Row(i)
+	return append([]byte(nil), col.col.Row(i)...)
 }
 
 var _ Interface = (*FixedString)(nil)
@@ -0,0 +1,60 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedStringScanArray(t *testing.T) {
+	col, err := Type("FixedString(16)").Column("id", nil)
+	require.NoError(t, err)
+	require.NoError(t, col.AppendRow("0123456789abcdef"))
+	require.NoError(t, col.AppendRow([]byte{0xff, 0, 1}))
+	decoded := roundTrip(t, col)
+
+	var id [16]byte
+	require.NoError(t, decoded.ScanRow(&id, 0))
+	assert.Equal(t, [16]byte([]byte("0123456789abcdef")), id)
+	require.NoError(t, decoded.ScanRow(&id, 1))
+	assert.Equal(t, [16]byte{0xff, 0, 1}, id, "padded with zero bytes")
+
+	t.Run("size mismatch", func(t *testing.T) {
+		var short [8]byte
+		err := decoded.ScanRow(&short, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid size 8, expect 16")
+		assert.Equal(t, [8]byte{}, short)
+	})
+}
+
+func BenchmarkFixedStringScanArray(b *testing.B) {
+	col, err := Type("FixedString(16)").Column("id", nil)
+	require.NoError(b, err)
+	require.NoError(b, col.AppendRow("0123456789abcdef"))
+	var id [16]byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := col.ScanRow(&id, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
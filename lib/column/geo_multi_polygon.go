@@ -66,6 +66,8 @@ func (col *MultiPolygon) ScanRow(dest any, row int) error {
 	case **orb.MultiPolygon:
 		*d = new(orb.MultiPolygon)
 		**d = col.row(row)
+	case *[][][][2]float64:
+		*d = multiPolygonCoordinates(col.row(row))
 	default:
 		return &ColumnConverterError{
 			Op:   "ScanRow",
@@ -91,6 +93,12 @@ func (col *MultiPolygon) Append(v any) (nulls []uint8, err error) {
 			values = append(values, *v)
 		}
 		return col.set.Append(values)
+	case [][][][][2]float64:
+		values := make([][]orb.Polygon, 0, len(v))
+		for _, v := range v {
+			values = append(values, multiPolygonFromCoordinates(v))
+		}
+		return col.set.Append(values)
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -118,6 +126,8 @@ func (col *MultiPolygon) AppendRow(v any) error {
 		return col.set.AppendRow([]orb.Polygon(v))
 	case *orb.MultiPolygon:
 		return col.set.AppendRow([]orb.Polygon(*v))
+	case [][][][2]float64:
+		return col.set.AppendRow([]orb.Polygon(multiPolygonFromCoordinates(v)))
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -155,4 +165,20 @@ func (col *MultiPolygon) row(i int) orb.MultiPolygon {
 	return value
 }
 
+func multiPolygonCoordinates(multiPolygon orb.MultiPolygon) [][][][2]float64 {
+	coordinates := make([][][][2]float64, len(multiPolygon))
+	for i, polygon := range multiPolygon {
+		coordinates[i] = polygonCoordinates(polygon)
+	}
+	return coordinates
+}
+
+func multiPolygonFromCoordinates(coordinates [][][][2]float64) orb.MultiPolygon {
+	multiPolygon := make(orb.MultiPolygon, len(coordinates))
+	for i, polygon := range coordinates {
+		multiPolygon[i] = polygonFromCoordinates(polygon)
+	}
+	return multiPolygon
+}
+
 var _ Interface = (*MultiPolygon)(nil)
@@ -66,6 +66,8 @@ func (col *Point) ScanRow(dest any, row int) error {
 	case **orb.Point:
 		*d = new(orb.Point)
 		**d = col.row(row)
+	case *[2]float64:
+		*d = col.row(row)
 	default:
 		return &ColumnConverterError{
 			Op:   "ScanRow",
@@ -95,6 +97,14 @@ func (col *Point) Append(v any) (nulls []uint8, err error) {
 				Y: v.Lat(),
 			})
 		}
+	case [][2]float64:
+		nulls = make([]uint8, len(v))
+		for _, v := range v {
+			col.col.Append(proto.Point{
+				X: v[0],
+				Y: v[1],
+			})
+		}
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -128,6 +138,11 @@ func (col *Point) AppendRow(v any) error {
 			X: v.Lon(),
 			Y: v.Lat(),
 		})
+	case [2]float64:
+		col.col.Append(proto.Point{
+			X: v[0],
+			Y: v[1],
+		})
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
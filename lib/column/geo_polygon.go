@@ -66,6 +66,8 @@ func (col *Polygon) ScanRow(dest any, row int) error {
 	case **orb.Polygon:
 		*d = new(orb.Polygon)
 		**d = col.row(row)
+	case *[][][2]float64:
+		*d = polygonCoordinates(col.row(row))
 	default:
 		return &ColumnConverterError{
 			Op:   "ScanRow",
@@ -91,6 +93,12 @@ func (col *Polygon) Append(v any) (nulls []uint8, err error) {
 			values = append(values, *v)
 		}
 		return col.set.Append(values)
+	case [][][][2]float64:
+		values := make([][]orb.Ring, 0, len(v))
+		for _, v := range v {
+			values = append(values, polygonFromCoordinates(v))
+		}
+		return col.set.Append(values)
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -118,6 +126,8 @@ func (col *Polygon) AppendRow(v any) error {
 		return col.set.AppendRow([]orb.Ring(v))
 	case *orb.Polygon:
 		return col.set.AppendRow([]orb.Ring(*v))
+	case [][][2]float64:
+		return col.set.AppendRow([]orb.Ring(polygonFromCoordinates(v)))
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -155,4 +165,21 @@ func (col *Polygon) row(i int) orb.Polygon {
 	return value
 }
 
+// polygonCoordinates returns the rings of a polygon as plain [lon, lat] pairs.
+func polygonCoordinates(polygon orb.Polygon) [][][2]float64 {
+	coordinates := make([][][2]float64, len(polygon))
+	for i, ring := range polygon {
+		coordinates[i] = ringCoordinates(ring)
+	}
+	return coordinates
+}
+
+func polygonFromCoordinates(coordinates [][][2]float64) orb.Polygon {
+	polygon := make(orb.Polygon, len(coordinates))
+	for i, ring := range coordinates {
+		polygon[i] = ringFromCoordinates(ring)
+	}
+	return polygon
+}
+
 var _ Interface = (*Polygon)(nil)
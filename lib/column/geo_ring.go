@@ -66,6 +66,8 @@ func (col *Ring) ScanRow(dest any, row int) error {
 	case **orb.Ring:
 		*d = new(orb.Ring)
 		**d = col.row(row)
+	case *[][2]float64:
+		*d = ringCoordinates(col.row(row))
 	default:
 		return &ColumnConverterError{
 			Op:   "ScanRow",
@@ -91,6 +93,12 @@ func (col *Ring) Append(v any) (nulls []uint8, err error) {
 			values = append(values, *v)
 		}
 		return col.set.Append(values)
+	case [][][2]float64:
+		values := make([][]orb.Point, 0, len(v))
+		for _, v := range v {
+			values = append(values, ringFromCoordinates(v))
+		}
+		return col.set.Append(values)
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -118,6 +126,8 @@ func (col *Ring) AppendRow(v any) error {
 		return col.set.AppendRow([]orb.Point(v))
 	case *orb.Ring:
 		return col.set.AppendRow([]orb.Point(*v))
+	case [][2]float64:
+		return col.set.AppendRow([]orb.Point(ringFromCoordinates(v)))
 	default:
 		if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -155,4 +165,21 @@ func (col *Ring) row(i int) orb.Ring {
 	return value
 }
 
+// ringCoordinates returns the points of a ring as plain [lon, lat] pairs.
+func ringCoordinates(ring orb.Ring) [][2]float64 {
+	coordinates := make([][2]float64, len(ring))
+	for i, point := range ring {
+		coordinates[i] = point
+	}
+	return coordinates
+}
+
+func ringFromCoordinates(coordinates [][2]float64) orb.Ring {
+	ring := make(orb.Ring, len(coordinates))
+	for i, point := range coordinates {
+		ring[i] = point
+	}
+	return ring
+}
+
 var _ Interface = (*Ring)(nil)
@@ -0,0 +1,108 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// geoWire lays out values as ClickHouse sends them: UInt64 array offsets and Float64 coordinates.
+func geoWire(offsets []uint64, coordinates ...float64) []byte {
+	var buf []byte
+	for _, offset := range offsets {
+		buf = binary.LittleEndian.AppendUint64(buf, offset)
+	}
+	for _, c := range coordinates {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c))
+	}
+	return buf
+}
+
+func TestGeoPointDecode(t *testing.T) {
+	col, err := Type("Point").Column("p", nil)
+	require.NoError(t, err)
+	// Tuple(Float64, Float64), the x column followed by the y column
+	require.NoError(t, col.Decode(proto.NewReader(bytes.NewReader(geoWire(nil, 1.5, -3, 2.5, 4))), 2))
+
+	var (
+		point  [2]float64
+		orbPtr *orb.Point
+	)
+	require.NoError(t, col.ScanRow(&point, 0))
+	assert.Equal(t, [2]float64{1.5, 2.5}, point)
+	require.NoError(t, col.ScanRow(&orbPtr, 1))
+	assert.Equal(t, orb.Point{-3, 4}, *orbPtr)
+}
+
+func TestGeoPolygonDecode(t *testing.T) {
+	col, err := Type("Polygon").Column("p", nil)
+	require.NoError(t, err)
+	// Array(Ring) of Array(Point): the polygon offsets, the ring offsets then the x and y columns of the points
+	wire := geoWire([]uint64{1, 4}, 0, 1, 0, 0, 0, 0, 1, 0)
+	require.NoError(t, col.Decode(proto.NewReader(bytes.NewReader(wire)), 1))
+
+	var polygon [][][2]float64
+	require.NoError(t, col.ScanRow(&polygon, 0))
+	assert.Equal(t, [][][2]float64{{{0, 0}, {1, 0}, {0, 1}, {0, 0}}}, polygon)
+}
+
+func TestGeoPlainArraysRoundTrip(t *testing.T) {
+	var (
+		point        = [2]float64{1, 2}
+		ring         = [][2]float64{{0, 0}, {2, 0}, {0, 2}, {0, 0}}
+		polygon      = [][][2]float64{ring, {{0.5, 0.5}, {1, 0.5}, {0.5, 1}, {0.5, 0.5}}}
+		multiPolygon = [][][][2]float64{polygon, {ring}}
+	)
+	testCases := []struct {
+		chType Type
+		row    any
+		rows   any
+		dest   func() any
+	}{
+		{"Point", point, [][2]float64{point}, func() any { return new([2]float64) }},
+		{"Ring", ring, [][][2]float64{ring}, func() any { return new([][2]float64) }},
+		{"Polygon", polygon, [][][][2]float64{polygon}, func() any { return new([][][2]float64) }},
+		{"MultiPolygon", multiPolygon, [][][][][2]float64{multiPolygon}, func() any { return new([][][][2]float64) }},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.chType), func(t *testing.T) {
+			col, err := tc.chType.Column("geo", nil)
+			require.NoError(t, err)
+			require.NoError(t, col.AppendRow(tc.row))
+			_, err = col.Append(tc.rows)
+			require.NoError(t, err)
+
+			decoded := roundTrip(t, col)
+			require.Equal(t, 2, decoded.Rows())
+			for i := 0; i < 2; i++ {
+				dest := tc.dest()
+				require.NoError(t, decoded.ScanRow(dest, i))
+				assert.Equal(t, tc.row, reflect.ValueOf(dest).Elem().Interface())
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmallIntBoundaries(t *testing.T) {
+	testCases := []struct {
+		chType  Type
+		values  []any
+		encoded []byte
+	}{
+		{"Int8", []any{int8(math.MinInt8), int8(0), int8(math.MaxInt8)}, []byte{0x80, 0x00, 0x7f}},
+		{"Int16", []any{int16(math.MinInt16), int16(1), int16(math.MaxInt16)}, []byte{0x00, 0x80, 0x01, 0x00, 0xff, 0x7f}},
+		{"UInt8", []any{uint8(0), uint8(1), uint8(math.MaxUint8)}, []byte{0x00, 0x01, 0xff}},
+		{"UInt16", []any{uint16(0), uint16(0x0102), uint16(math.MaxUint16)}, []byte{0x00, 0x00, 0x02, 0x01, 0xff, 0xff}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.chType), func(t *testing.T) {
+			col, err := tc.chType.Column("v", nil)
+			require.NoError(t, err)
+			for _, v := range tc.values {
+				require.NoError(t, col.AppendRow(v))
+			}
+
+			var buf proto.Buffer
+			col.Encode(&buf)
+			assert.Equal(t, tc.encoded, buf.Buf, "little-endian layout")
+
+			decoded := roundTrip(t, col)
+			require.Equal(t, len(tc.values), decoded.Rows())
+			for i, expected := range tc.values {
+				assert.Equal(t, expected, decoded.Row(i, false))
+				dest := reflect.New(reflect.TypeOf(expected))
+				require.NoError(t, decoded.ScanRow(dest.Interface(), i))
+				assert.Equal(t, expected, dest.Elem().Interface())
+			}
+		})
+	}
+}
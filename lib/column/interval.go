@@ -25,6 +25,12 @@ import (
 	"strings"
 )
 
+// IntervalValue is a value of an Interval type, the number of units and the unit, e.g. Day for IntervalDay.
+type IntervalValue struct {
+	Value int64
+	Unit  string
+}
+
 type Interval struct {
 	chType Type
 	name   string
@@ -41,7 +47,8 @@ func (col *Interval) Name() string {
 
 func (col *Interval) parse(t Type) (Interface, error) {
 	switch col.chType = t; col.chType {
-	case "IntervalSecond", "IntervalMinute", "IntervalHour", "IntervalDay", "IntervalWeek", "IntervalMonth", "IntervalYear":
+	case "IntervalNanosecond", "IntervalMicrosecond", "IntervalMillisecond",
+		"IntervalSecond", "IntervalMinute", "IntervalHour", "IntervalDay", "IntervalWeek", "IntervalMonth", "IntervalQuarter", "IntervalYear":
 		return col, nil
 	}
 	return nil, &UnsupportedColumnTypeError{
@@ -66,6 +73,16 @@ func (col *Interval) ScanRow(dest any, row int) error {
 	case **string:
 		*d = new(string)
 		**d = col.row(row)
+	case *IntervalValue:
+		*d = col.value(row)
+	case **IntervalValue:
+		*d = new(IntervalValue)
+		**d = col.value(row)
+	case *int64:
+		*d = col.col.Row(row)
+	case **int64:
+		*d = new(int64)
+		**d = col.col.Row(row)
 	default:
 		return &ColumnConverterError{
 			Op:   "ScanRow",
@@ -97,9 +114,17 @@ func (col *Interval) Decode(reader *proto.Reader, rows int) error {
 func (Interval) Encode(buffer *proto.Buffer) {
 }
 
+func (col *Interval) unit() string {
+	return strings.TrimPrefix(string(col.chType), "Interval")
+}
+
+func (col *Interval) value(i int) IntervalValue {
+	return IntervalValue{Value: col.col.Row(i), Unit: col.unit()}
+}
+
 func (col *Interval) row(i int) string {
 	val := col.col.Row(i)
-	v := fmt.Sprintf("%d %s", val, strings.TrimPrefix(string(col.chType), "Interval"))
+	v := fmt.Sprintf("%d %s", val, col.unit())
 	if val > 1 {
 		v += "s"
 	}
@@ -0,0 +1,73 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterval(t *testing.T) {
+	testCases := []struct {
+		chType Type
+		unit   string
+		text   string
+	}{
+		{"IntervalDay", "Day", "3 Days"},
+		{"IntervalSecond", "Second", "3 Seconds"},
+		{"IntervalMillisecond", "Millisecond", "3 Milliseconds"},
+		{"IntervalQuarter", "Quarter", "3 Quarters"},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.chType), func(t *testing.T) {
+			col, err := tc.chType.Column("dateDiff", nil)
+			require.NoError(t, err)
+
+			var values proto.ColInt64
+			values.Append(3)
+			values.Append(-1)
+			var buf proto.Buffer
+			values.EncodeColumn(&buf)
+			require.NoError(t, col.Decode(proto.NewReader(bytes.NewReader(buf.Buf)), values.Rows()))
+
+			var (
+				value IntervalValue
+				ptr   *IntervalValue
+				raw   int64
+				text  string
+			)
+			require.NoError(t, col.ScanRow(&value, 0))
+			require.NoError(t, col.ScanRow(&ptr, 1))
+			require.NoError(t, col.ScanRow(&raw, 1))
+			require.NoError(t, col.ScanRow(&text, 0))
+			assert.Equal(t, IntervalValue{Value: 3, Unit: tc.unit}, value)
+			assert.Equal(t, &IntervalValue{Value: -1, Unit: tc.unit}, ptr)
+			assert.Equal(t, int64(-1), raw)
+			assert.Equal(t, tc.text, text)
+		})
+	}
+
+	t.Run("unknown unit", func(t *testing.T) {
+		_, err := Type("IntervalFortnight").Column("x", nil)
+		assert.Error(t, err)
+	})
+}
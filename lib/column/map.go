@@ -306,6 +306,8 @@ func (col *Map) WriteStatePrefix(encoder *proto.Buffer) error {
 	return nil
 }
 
+// row builds the Go map for row n. ClickHouse permits duplicate keys within a map on the
+// wire, in which case the last value wins.
 func (col *Map) row(n int) reflect.Value {
 	var (
 		prev  int64
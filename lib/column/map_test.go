@@ -0,0 +1,95 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pairs is an IterableOrderedMap that, unlike a Go map, can hold duplicate keys.
+type pairs struct {
+	keys, values []any
+}
+
+func (p *pairs) Put(key any, value any) {
+	p.keys, p.values = append(p.keys, key), append(p.values, value)
+}
+
+func (p *pairs) Iterator() MapIterator {
+	return &pairsIterator{pairs: p, i: -1}
+}
+
+type pairsIterator struct {
+	pairs *pairs
+	i     int
+}
+
+func (it *pairsIterator) Next() bool { it.i++; return it.i < len(it.pairs.keys) }
+func (it *pairsIterator) Key() any   { return it.pairs.keys[it.i] }
+func (it *pairsIterator) Value() any { return it.pairs.values[it.i] }
+
+func roundTrip(t *testing.T, col Interface) Interface {
+	var buf proto.Buffer
+	col.Encode(&buf)
+	decoded, err := col.Type().Column(col.Name(), nil)
+	require.NoError(t, err)
+	require.NoError(t, decoded.Decode(proto.NewReader(bytes.NewReader(buf.Buf)), col.Rows()))
+	return decoded
+}
+
+func TestMapRoundTrip(t *testing.T) {
+	col, err := Type("Map(String, UInt64)").Column("m", nil)
+	require.NoError(t, err)
+
+	rows := []map[string]uint64{
+		{"a": 1, "b": 2},
+		{},
+		{"c": 3},
+	}
+	for _, row := range rows {
+		require.NoError(t, col.AppendRow(row))
+	}
+
+	decoded := roundTrip(t, col)
+	require.Equal(t, len(rows), decoded.Rows())
+	for i, expected := range rows {
+		var row map[string]uint64
+		require.NoError(t, decoded.ScanRow(&row, i))
+		assert.Equal(t, expected, row)
+	}
+}
+
+func TestMapDuplicateKeys(t *testing.T) {
+	col, err := Type("Map(String, UInt64)").Column("m", nil)
+	require.NoError(t, err)
+
+	row := &pairs{}
+	row.Put("a", uint64(1))
+	row.Put("b", uint64(2))
+	row.Put("a", uint64(3))
+	require.NoError(t, col.AppendRow(row))
+
+	var values map[string]uint64
+	require.NoError(t, roundTrip(t, col).ScanRow(&values, 0))
+	assert.Equal(t, map[string]uint64{"a": 3, "b": 2}, values)
+}
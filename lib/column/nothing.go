@@ -32,35 +32,38 @@ func (col *Nothing) Reset() {
 	col.col.Reset()
 }
 
-func (col Nothing) Name() string {
+func (col *Nothing) Name() string {
 	return col.name
 }
 
-func (Nothing) Type() Type             { return "Nothing" }
-func (Nothing) ScanType() reflect.Type { return reflect.TypeOf((*any)(nil)) }
-func (Nothing) Rows() int              { return 0 }
-func (Nothing) Row(int, bool) any      { return nil }
-func (Nothing) ScanRow(any, int) error {
-	return nil
+func (*Nothing) Type() Type             { return "Nothing" }
+func (*Nothing) ScanType() reflect.Type { return reflect.TypeOf((*any)(nil)) }
+func (col *Nothing) Rows() int          { return col.col.Rows() }
+func (*Nothing) Row(int, bool) any      { return nil }
+
+// ScanRow always yields NULL, there are no values of type Nothing.
+func (*Nothing) ScanRow(dest any, _ int) error {
+	return scanNull(dest)
 }
-func (Nothing) Append(any) ([]uint8, error) {
+func (*Nothing) Append(any) ([]uint8, error) {
 	return nil, &Error{
 		ColumnType: "Nothing",
 		Err:        errors.New("data type values can't be stored in tables"),
 	}
 }
-func (col Nothing) AppendRow(any) error {
+func (col *Nothing) AppendRow(any) error {
 	return &Error{
 		ColumnType: "Nothing",
 		Err:        errors.New("data type values can't be stored in tables"),
 	}
 }
 
-func (col Nothing) Decode(reader *proto.Reader, rows int) error {
+func (col *Nothing) Decode(reader *proto.Reader, rows int) error {
 	return col.col.DecodeColumn(reader, rows)
 }
 
-func (Nothing) Encode(buffer *proto.Buffer) {
+func (col *Nothing) Encode(buffer *proto.Buffer) {
+	col.col.EncodeColumn(buffer)
 }
 
 var _ Interface = (*Nothing)(nil)
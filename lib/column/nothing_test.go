@@ -0,0 +1,70 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullConstant(t *testing.T) {
+	const rows = 3
+	testCases := []struct {
+		name   string // the query returning the column
+		chType Type
+		width  int // bytes per value following the null map
+	}{
+		{"SELECT NULL", "Nullable(Nothing)", 1},
+		{"SELECT CAST(NULL AS Nullable(Int32))", "Nullable(Int32)", 4},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			col, err := tc.chType.Column("NULL", nil)
+			require.NoError(t, err)
+
+			// every row is NULL followed by zeroed placeholder values, and a byte that must be left unread
+			data := append(bytes.Repeat([]byte{1}, rows), make([]byte, rows*tc.width)...)
+			reader := proto.NewReader(bytes.NewReader(append(data, 0xff)))
+			require.NoError(t, col.Decode(reader, rows))
+			next, err := reader.ReadByte()
+			require.NoError(t, err)
+			assert.Equal(t, byte(0xff), next)
+
+			require.Equal(t, rows, col.Rows())
+			for i := 0; i < rows; i++ {
+				assert.Nil(t, col.Row(i, false))
+				var (
+					value    any = "not null"
+					ptr          = new(int32)
+					nullable     = sql.NullInt32{Int32: 1, Valid: true}
+				)
+				require.NoError(t, col.ScanRow(&value, i))
+				require.NoError(t, col.ScanRow(&ptr, i))
+				require.NoError(t, col.ScanRow(&nullable, i))
+				assert.Nil(t, value)
+				assert.Nil(t, ptr)
+				assert.False(t, nullable.Valid)
+			}
+		})
+	}
+}
@@ -115,6 +115,8 @@ func (col *Nullable) ScanRow(dest any, row int) error {
 				*v = nil
 			case **time.Time:
 				*v = nil
+			default:
+				return scanNull(dest)
 			}
 			if scan, ok := dest.(sql.Scanner); ok {
 				return scan.Scan(nil)
@@ -125,6 +127,21 @@ func (col *Nullable) ScanRow(dest any, row int) error {
 	return col.base.ScanRow(dest, row)
 }
 
+// scanNull stores NULL into dest: a sql.Scanner receives nil and pointers to values that can
+// hold nil, like *any or **T, are reset. Other destinations are left untouched.
+func scanNull(dest any) error {
+	if scan, ok := dest.(sql.Scanner); ok {
+		return scan.Scan(nil)
+	}
+	if rv := reflect.ValueOf(dest); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		switch elem := rv.Elem(); elem.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			elem.Set(reflect.Zero(elem.Type()))
+		}
+	}
+	return nil
+}
+
 func (col *Nullable) Append(v any) ([]uint8, error) {
 	nulls, err := col.base.Append(v)
 	if err != nil {
@@ -164,6 +181,21 @@ func (col *Nullable) AppendRow(v any) error {
 	return col.base.AppendRow(v)
 }
 
+// ReadStatePrefix reads the prefix of the nested type, there is none of the null map.
+func (col *Nullable) ReadStatePrefix(reader *proto.Reader) error {
+	if serialize, ok := col.base.(CustomSerialization); ok {
+		return serialize.ReadStatePrefix(reader)
+	}
+	return nil
+}
+
+func (col *Nullable) WriteStatePrefix(buffer *proto.Buffer) error {
+	if serialize, ok := col.base.(CustomSerialization); ok {
+		return serialize.WriteStatePrefix(buffer)
+	}
+	return nil
+}
+
 func (col *Nullable) Decode(reader *proto.Reader, rows int) error {
 	if col.enable {
 		if err := col.nulls.DecodeColumn(reader, rows); err != nil {
@@ -183,4 +215,7 @@ func (col *Nullable) Encode(buffer *proto.Buffer) {
 	col.base.Encode(buffer)
 }
 
-var _ Interface = (*Nullable)(nil)
+var (
+	_ Interface           = (*Nullable)(nil)
+	_ CustomSerialization = (*Nullable)(nil)
+)
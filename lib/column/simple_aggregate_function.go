@@ -40,9 +40,11 @@ func (col *SimpleAggregateFunction) Name() string {
 
 func (col *SimpleAggregateFunction) parse(t Type, tz *time.Location) (_ Interface, err error) {
 	col.chType = t
-	base := strings.TrimSpace(strings.SplitN(t.params(), ",", 2)[1])
-	if col.base, err = Type(base).Column(col.name, tz); err == nil {
-		return col, nil
+	// the values are those of the type following the function name, e.g. UInt64 for SimpleAggregateFunction(sum, UInt64)
+	if _, base, ok := strings.Cut(t.params(), ","); ok {
+		if col.base, err = Type(strings.TrimSpace(base)).Column(col.name, tz); err == nil {
+			return col, nil
+		}
 	}
 	return nil, &UnsupportedColumnTypeError{
 		t: t,
@@ -77,4 +79,22 @@ func (col *SimpleAggregateFunction) Encode(buffer *proto.Buffer) {
 	col.base.Encode(buffer)
 }
 
-var _ Interface = (*SimpleAggregateFunction)(nil)
+// ReadStatePrefix reads the prefix of the value type, e.g. for SimpleAggregateFunction(anyLast, LowCardinality(String)).
+func (col *SimpleAggregateFunction) ReadStatePrefix(reader *proto.Reader) error {
+	if serialize, ok := col.base.(CustomSerialization); ok {
+		return serialize.ReadStatePrefix(reader)
+	}
+	return nil
+}
+
+func (col *SimpleAggregateFunction) WriteStatePrefix(buffer *proto.Buffer) error {
+	if serialize, ok := col.base.(CustomSerialization); ok {
+		return serialize.WriteStatePrefix(buffer)
+	}
+	return nil
+}
+
+var (
+	_ Interface           = (*SimpleAggregateFunction)(nil)
+	_ CustomSerialization = (*SimpleAggregateFunction)(nil)
+)
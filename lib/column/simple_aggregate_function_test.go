@@ -0,0 +1,64 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimpleAggregateFunction(t *testing.T) {
+	col, err := Type("SimpleAggregateFunction(sum, UInt64)").Column("total", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Type("SimpleAggregateFunction(sum, UInt64)"), col.Type())
+	assert.Equal(t, scanTypeUInt64, col.ScanType())
+
+	// the values are laid out exactly as UInt64 ones
+	var wire []byte
+	for _, v := range []uint64{3, 1 << 40} {
+		wire = binary.LittleEndian.AppendUint64(wire, v)
+	}
+	require.NoError(t, col.Decode(proto.NewReader(bytes.NewReader(wire)), 2))
+	var total uint64
+	require.NoError(t, col.ScanRow(&total, 1))
+	assert.EqualValues(t, 1<<40, total)
+	assert.Equal(t, uint64(3), col.Row(0, false))
+
+	t.Run("nested types", func(t *testing.T) {
+		col, err := Type("SimpleAggregateFunction(anyLast, Nullable(String))").Column("last", nil)
+		require.NoError(t, err)
+		require.NoError(t, col.AppendRow(nil))
+		require.NoError(t, col.AppendRow("x"))
+		decoded := roundTrip(t, col)
+		assert.Nil(t, decoded.Row(0, false))
+		var last *string
+		require.NoError(t, decoded.ScanRow(&last, 1))
+		require.NotNil(t, last)
+		assert.Equal(t, "x", *last)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := Type("SimpleAggregateFunction(sum)").Column("total", nil)
+		assert.Error(t, err)
+	})
+}
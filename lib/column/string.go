@@ -21,16 +21,23 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding"
+	"errors"
 	"fmt"
 	"github.com/ClickHouse/ch-go/proto"
 	"reflect"
+	"slices"
 
 	"github.com/ClickHouse/clickhouse-go/v2/lib/binary"
 )
 
+// ErrStringTooLarge is returned by Decode for a string longer than the limit set with LimitStringSize.
+var ErrStringTooLarge = errors.New("clickhouse: string exceeds the maximum allowed size")
+
 type String struct {
 	name string
 	col  proto.ColStr
+	// maxSize bounds the strings read by Decode, zero means no limit, see LimitStringSize
+	maxSize int
 }
 
 func (col *String) Reset() {
@@ -200,11 +207,58 @@ func (col *String) Append(v any) (nulls []uint8, err error) {
 }
 
 func (col *String) Decode(reader *proto.Reader, rows int) error {
-	return col.col.DecodeColumn(reader, rows)
+	if col.maxSize <= 0 {
+		return col.col.DecodeColumn(reader, rows)
+	}
+	col.col.Pos = col.col.Pos[:0]
+	col.col.Buf = col.col.Buf[:0]
+	for i := 0; i < rows; i++ {
+		n, err := reader.StrLen()
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		// the length prefix is checked before the string is allocated
+		if n > col.maxSize {
+			return fmt.Errorf("%w: row %d, length %d, limit %d", ErrStringTooLarge, i, n, col.maxSize)
+		}
+		start := len(col.col.Buf)
+		col.col.Buf = slices.Grow(col.col.Buf, n)[:start+n]
+		if err := reader.ReadFull(col.col.Buf[start:]); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		col.col.Pos = append(col.col.Pos, proto.Position{Start: start, End: start + n})
+	}
+	return nil
 }
 
 func (col *String) Encode(buffer *proto.Buffer) {
 	col.col.EncodeColumn(buffer)
 }
 
+// LimitStringSize makes the String columns of col, the nested ones included, fail to decode a string
+// longer than max bytes rather than allocate it.
+func LimitStringSize(col Interface, max int) {
+	switch col := col.(type) {
+	case *String:
+		col.maxSize = max
+	case *Array:
+		LimitStringSize(col.values, max)
+	case *Map:
+		LimitStringSize(col.keys, max)
+		LimitStringSize(col.values, max)
+	case *Tuple:
+		for _, c := range col.columns {
+			LimitStringSize(c, max)
+		}
+	case *Nullable:
+		LimitStringSize(col.base, max)
+	case *LowCardinality:
+		LimitStringSize(col.index, max)
+	case *SimpleAggregateFunction:
+		LimitStringSize(col.base, max)
+	case *Nested:
+		LimitStringSize(col.Interface, max)
+	}
+}
+
 var _ Interface = (*String)(nil)
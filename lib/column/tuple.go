@@ -229,9 +229,14 @@ func setJSONFieldValue(field reflect.Value, value reflect.Value) error {
 
 }
 
+// getStructFieldValue returns the exported field of a struct for the tuple element name, the
+// unexported ones can neither be read nor set.
 func getStructFieldValue(field reflect.Value, name string) (reflect.Value, bool) {
 	tField := field.Type()
 	for i := 0; i < tField.NumField(); i++ {
+		if !tField.Field(i).IsExported() {
+			continue
+		}
 		if tag := tField.Field(i).Tag.Get("json"); tag == name {
 			return field.Field(i), true
 		}
@@ -239,8 +244,11 @@ func getStructFieldValue(field reflect.Value, name string) (reflect.Value, bool)
 			return field.Field(i), true
 		}
 	}
-	sField := field.FieldByName(name)
-	return sField, sField.IsValid()
+	sField, ok := tField.FieldByName(name)
+	if !ok || !sField.IsExported() {
+		return reflect.Value{}, false
+	}
+	return field.FieldByIndex(sField.Index), true
 }
 
 func unescapeColName(colName string) string {
@@ -325,10 +333,41 @@ func (col *Tuple) scanMap(targetMap reflect.Value, row int) error {
 	return nil
 }
 
+// structFields returns the struct fields matching the tuple elements. Named tuples are matched
+// by name (or json/ch tag), unnamed tuples positionally against the exported fields.
+func (col *Tuple) structFields(value reflect.Value) ([]reflect.Value, error) {
+	fields := make([]reflect.Value, len(col.columns))
+	if col.isNamed {
+		for i, c := range col.columns {
+			// the column may be serialized using a different name due to a struct tag
+			if field, ok := getStructFieldValue(value, c.Name()); ok {
+				fields[i] = field
+			}
+		}
+		return fields, nil
+	}
+	var exported []reflect.Value
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).IsExported() {
+			exported = append(exported, value.Field(i))
+		}
+	}
+	if len(exported) != len(col.columns) {
+		return nil, &Error{
+			ColumnType: string(col.chType),
+			Err:        fmt.Errorf("invalid size. expected %d got %d exported fields in %s", len(col.columns), len(exported), value.Type()),
+		}
+	}
+	return exported, nil
+}
+
 func (col *Tuple) scanStruct(targetStruct reflect.Value, row int) error {
-	for _, c := range col.columns {
-		// the column may be serialized using a different name due to a struct "targetStruct" tag
-		sField, ok := getStructFieldValue(targetStruct, c.Name())
+	fields, err := col.structFields(targetStruct)
+	if err != nil {
+		return err
+	}
+	for i, c := range col.columns {
+		sField, ok := fields[i], fields[i].IsValid()
 		// test if map
 		if !ok {
 			continue
@@ -564,6 +603,28 @@ func (col *Tuple) AppendRow(v any) error {
 			}
 		}
 		return nil
+	case reflect.Struct:
+		if _, ok := v.(driver.Valuer); ok {
+			break
+		}
+		fields, err := col.structFields(value)
+		if err != nil {
+			return err
+		}
+		for i, field := range fields {
+			if !field.IsValid() {
+				return &ColumnConverterError{
+					Op:   "AppendRow",
+					To:   string(col.chType),
+					From: value.Type().String(),
+					Hint: fmt.Sprintf("sub column '%s' has no matching exported field", col.columns[i].Name()),
+				}
+			}
+			if err := col.columns[i].AppendRow(field.Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	if valuer, ok := v.(driver.Valuer); ok {
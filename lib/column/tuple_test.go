@@ -0,0 +1,88 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package column
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTupleRoundTrip(t *testing.T) {
+	type row struct {
+		ID   uint32
+		Name string
+	}
+
+	col, err := Type("Tuple(UInt32, String)").Column("t", nil)
+	require.NoError(t, err)
+	require.NoError(t, col.AppendRow([]any{uint32(1), "a"}))
+	require.NoError(t, col.AppendRow(row{ID: 2, Name: "b"}))
+	require.NoError(t, col.AppendRow(&row{ID: 3, Name: "c"}))
+
+	var buf proto.Buffer
+	col.Encode(&buf)
+
+	decoded, err := Type("Tuple(UInt32, String)").Column("t", nil)
+	require.NoError(t, err)
+	require.NoError(t, decoded.Decode(proto.NewReader(bytes.NewReader(buf.Buf)), 3))
+	require.Equal(t, 3, decoded.Rows())
+
+	var values []any
+	require.NoError(t, decoded.ScanRow(&values, 0))
+	assert.Equal(t, []any{uint32(1), "a"}, values)
+
+	var scanned row
+	require.NoError(t, decoded.ScanRow(&scanned, 1))
+	assert.Equal(t, row{ID: 2, Name: "b"}, scanned)
+	require.NoError(t, decoded.ScanRow(&scanned, 2))
+	assert.Equal(t, row{ID: 3, Name: "c"}, scanned)
+}
+
+func TestTupleAppendStructArity(t *testing.T) {
+	col, err := Type("Tuple(UInt32, String)").Column("t", nil)
+	require.NoError(t, err)
+
+	err = col.AppendRow(struct{ ID uint32 }{ID: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid size")
+	assert.Equal(t, 0, col.Rows())
+}
+
+func TestTupleUnexportedStructField(t *testing.T) {
+	type row struct {
+		name string
+		Age  uint8
+	}
+	col, err := Type("Tuple(name String, Age UInt8)").Column("t", nil)
+	require.NoError(t, err)
+
+	err = col.AppendRow(row{name: "a", Age: 1})
+	var converterErr *ColumnConverterError
+	require.ErrorAs(t, err, &converterErr)
+	assert.Contains(t, err.Error(), "'name'")
+	assert.Equal(t, 0, col.Rows())
+
+	require.NoError(t, col.AppendRow(map[string]any{"name": "b", "Age": uint8(2)}))
+	var scanned row
+	require.NoError(t, col.ScanRow(&scanned, 0))
+	assert.Equal(t, row{Age: 2}, scanned, "the unexported field is left alone")
+}
@@ -22,10 +22,15 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
-type ServerVersion = proto.ServerHandshake
+type (
+	ServerVersion = proto.ServerHandshake
+	TableName     = proto.TableName
+	TableStatus   = proto.TableStatus
+)
 
 type (
 	NamedValue struct {
@@ -39,6 +44,14 @@ type (
 		Scale uint8
 	}
 
+	// Block is a block of rows streamed by BlocksConn.QueryBlocks, each column holds the name, the type
+	// and the values of the column for all rows of the block. Err is only set on a last block
+	// without columns, when reading the result failed.
+	Block struct {
+		Columns []column.Interface
+		Err     error
+	}
+
 	Stats struct {
 		MaxOpenConns int
 		MaxIdleConns int
@@ -61,6 +74,28 @@ type (
 		Stats() Stats
 		Close() error
 	}
+	// SettingsConn is implemented by the Conn returned by clickhouse.Open, the settings set on it
+	// are sent with every following query on any connection of the pool:
+	//
+	//	conn.(driver.SettingsConn).SetSetting("max_threads", 4)
+	SettingsConn interface {
+		// SetSetting sets a default that is sent with every following query on any connection
+		// of the pool. Settings passed with the query context take precedence.
+		SetSetting(name string, value any)
+		// ClearSettings removes all defaults set with SetSetting.
+		ClearSettings()
+	}
+	// BlocksConn is implemented by the Conn returned by clickhouse.Open.
+	BlocksConn interface {
+		// QueryBlocks streams the result of a query block by block, column-oriented as it's
+		// read, rather than row by row. The channel is closed once the result is read.
+		QueryBlocks(ctx context.Context, query string, args ...any) (<-chan Block, error)
+	}
+	// TablesStatusConn is implemented by the Conn returned by clickhouse.Open.
+	TablesStatusConn interface {
+		// TablesStatus reports the replication state of the tables on the server, native protocol only.
+		TablesStatus(ctx context.Context, tables []TableName) (map[TableName]TableStatus, error)
+	}
 	Row interface {
 		Err() error
 		Scan(dest ...any) error
@@ -76,6 +111,14 @@ type (
 		Close() error
 		Err() error
 	}
+	// ExtremesRows is implemented by the Rows of Conn.Query:
+	//
+	//	rows.(driver.ExtremesRows).Extremes([]any{&min}, []any{&max})
+	ExtremesRows interface {
+		// Extremes scans the minimum and the maximum rows of a query run with extremes=1, once
+		// the rows are read.
+		Extremes(min, max []any) error
+	}
 	Batch interface {
 		Abort() error
 		Append(v ...any) error
@@ -32,8 +32,20 @@ type Block struct {
 	Packet   byte
 	Columns  []column.Interface
 	Timezone *time.Location
+	// MaxStringSize bounds the column names and types and the values of String columns read by Decode,
+	// zero means DefaultMaxStringSize
+	MaxStringSize int
+	// MaxRows bounds the rows of a block read by Decode, zero means DefaultMaxBlockRows
+	MaxRows int
 }
 
+const (
+	// DefaultMaxBlockRows is the largest number of rows accepted in a block unless configured otherwise.
+	DefaultMaxBlockRows = 1_000_000_000
+	// maxBlockColumns is far above any real table, a larger count is a corrupted stream.
+	maxBlockColumns = 1_000_000
+)
+
 func (b *Block) Rows() int {
 	if len(b.Columns) == 0 {
 		return 0
@@ -195,12 +207,29 @@ func (b *Block) Decode(reader *proto.Reader, revision uint64) (err error) {
 	if numRows, err = reader.UVarInt(); err != nil {
 		return err
 	}
-	if numRows > 1_000_000_000 {
+	if numCols > maxBlockColumns {
+		return &BlockError{
+			Op:  "Decode",
+			Err: fmt.Errorf("%d columns in block - suspiciously big - preventing OOM", numCols),
+		}
+	}
+	maxRows := uint64(DefaultMaxBlockRows)
+	if b.MaxRows > 0 {
+		maxRows = uint64(b.MaxRows)
+	}
+	if numRows > maxRows {
 		return &BlockError{
 			Op:  "Decode",
-			Err: errors.New("more then 1 billion rows in block - suspiciously big - preventing OOM"),
+			Err: fmt.Errorf("%d rows in block exceed the limit of %d - preventing OOM", numRows, maxRows),
 		}
 	}
+	maxStringSize := b.MaxStringSize
+	if maxStringSize <= 0 {
+		maxStringSize = DefaultMaxStringSize
+	}
+	// a recycled block keeps its columns, which are reused when the structure matches
+	// so their buffers don't have to be allocated again
+	reuse := b.Columns
 	b.Columns = make([]column.Interface, numCols, numCols)
 	b.names = make([]string, numCols, numCols)
 	for i := 0; i < int(numCols); i++ {
@@ -208,16 +237,20 @@ func (b *Block) Decode(reader *proto.Reader, revision uint64) (err error) {
 			columnName string
 			columnType string
 		)
-		if columnName, err = reader.Str(); err != nil {
+		if columnName, err = ReadString(reader, b.MaxStringSize); err != nil {
 			return err
 		}
-		if columnType, err = reader.Str(); err != nil {
+		if columnType, err = ReadString(reader, b.MaxStringSize); err != nil {
 			return err
 		}
-		c, err := column.Type(columnType).Column(columnName, b.Timezone)
-		if err != nil {
+		var c column.Interface
+		if i < len(reuse) && reuse[i].Name() == columnName && string(reuse[i].Type()) == columnType {
+			c = reuse[i]
+			c.Reset()
+		} else if c, err = column.Type(columnType).Column(columnName, b.Timezone); err != nil {
 			return err
 		}
+		column.LimitStringSize(c, maxStringSize)
 
 		if revision >= DBMS_MIN_REVISION_WITH_CUSTOM_SERIALIZATION {
 			hasCustom, err := reader.Bool()
@@ -270,11 +303,27 @@ func encodeBlockInfo(buffer *proto.Buffer) {
 	buffer.PutUVarInt(0)
 }
 
+// ErrUnexpectedCompression is returned when a data block arrives compressed on a connection that
+// did not negotiate compression, e.g. because a proxy compresses responses on its own.
+var ErrUnexpectedCompression = errors.New("received compressed block but compression disabled")
+
+// compressed frames start with a 16 byte checksum followed by the method byte
+const (
+	compressedChecksumSize = 16
+	compressionMethodNone  = 0x02
+	compressionMethodLZ4   = 0x82
+	compressionMethodZSTD  = 0x90
+)
+
 func decodeBlockInfo(reader *proto.Reader) error {
 	{
-		if _, err := reader.UVarInt(); err != nil {
+		field, err := reader.ReadByte()
+		if err != nil {
 			return err
 		}
+		if field != 1 {
+			return unexpectedBlockInfo(reader, field)
+		}
 		if _, err := reader.Bool(); err != nil {
 			return err
 		}
@@ -291,6 +340,20 @@ func decodeBlockInfo(reader *proto.Reader) error {
 	return nil
 }
 
+// unexpectedBlockInfo tells a compressed frame apart from a corrupt block when the block info
+// doesn't start with its first field.
+func unexpectedBlockInfo(reader *proto.Reader, field byte) error {
+	rest, err := reader.ReadRaw(compressedChecksumSize)
+	if err != nil {
+		return err
+	}
+	switch rest[compressedChecksumSize-1] {
+	case compressionMethodNone, compressionMethodLZ4, compressionMethodZSTD:
+		return &BlockError{Op: "Decode", Err: ErrUnexpectedCompression}
+	}
+	return &BlockError{Op: "Decode", Err: fmt.Errorf("unexpected block info field %d", field)}
+}
+
 type BlockError struct {
 	Op         string
 	Err        error
@@ -306,3 +369,7 @@ func (e *BlockError) Error() string {
 	}
 	return fmt.Sprintf("clickhouse [%s]: %s %s", e.Op, e.ColumnName, e.Err)
 }
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
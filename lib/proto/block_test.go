@@ -0,0 +1,155 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/compress"
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodedBlock(t testing.TB) []byte {
+	block := &Block{}
+	require.NoError(t, block.AddColumn("id", "UInt64"))
+	require.NoError(t, block.AddColumn("name", "String"))
+	require.NoError(t, block.AddColumn("tags", "Array(Nullable(String))"))
+	require.NoError(t, block.AddColumn("kind", "LowCardinality(String)"))
+	require.NoError(t, block.AddColumn("attrs", "Map(String, UInt64)"))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, block.Append(uint64(i), "name", []*string{nil}, "kind", map[string]uint64{"a": 1}))
+	}
+	var buf proto.Buffer
+	require.NoError(t, block.Encode(&buf, DBMS_TCP_PROTOCOL_VERSION))
+	return buf.Buf
+}
+
+func decode(data []byte, maxRows int) error {
+	block := &Block{MaxRows: maxRows}
+	return block.Decode(proto.NewReader(bytes.NewReader(data)), DBMS_TCP_PROTOCOL_VERSION)
+}
+
+func header(numCols, numRows uint64) []byte {
+	var buf proto.Buffer
+	encodeBlockInfo(&buf)
+	buf.PutUVarInt(numCols)
+	buf.PutUVarInt(numRows)
+	return buf.Buf
+}
+
+func TestBlockDecodeLimits(t *testing.T) {
+	require.NoError(t, decode(encodedBlock(t), 0))
+
+	err := decode(header(1<<40, 1), 0)
+	var blockErr *BlockError
+	require.ErrorAs(t, err, &blockErr)
+	assert.Contains(t, err.Error(), "columns in block")
+
+	err = decode(header(1, DefaultMaxBlockRows+1), 0)
+	require.ErrorAs(t, err, &blockErr)
+	assert.Contains(t, err.Error(), "rows in block")
+
+	err = decode(header(1, 11), 10)
+	require.ErrorAs(t, err, &blockErr)
+	assert.Contains(t, err.Error(), "limit of 10")
+
+	// a String value with an absurd length prefix is refused before it's allocated
+	var buf proto.Buffer
+	buf.PutRaw(header(1, 1))
+	buf.PutString("s")
+	buf.PutString("String")
+	buf.PutBool(false)
+	buf.PutUVarInt(1 << 40)
+	err = decode(buf.Buf, 0)
+	require.ErrorAs(t, err, &blockErr)
+	assert.ErrorIs(t, err, ErrStringTooLarge)
+}
+
+func TestBlockDecodeTruncated(t *testing.T) {
+	data := encodedBlock(t)
+	for n := 0; n < len(data); n++ {
+		assert.Error(t, decode(data[:n], 0), "truncated to %d of %d bytes", n, len(data))
+	}
+}
+
+func TestBlockDecodeUnexpectedCompression(t *testing.T) {
+	for _, method := range []compress.Method{compress.None, compress.LZ4, compress.ZSTD} {
+		t.Run(method.String(), func(t *testing.T) {
+			w := compress.NewWriter()
+			require.NoError(t, w.Compress(method, encodedBlock(t)))
+			err := decode(w.Data, 0)
+			assert.ErrorIs(t, err, ErrUnexpectedCompression)
+		})
+	}
+
+	t.Run("corrupt block info", func(t *testing.T) {
+		data := append([]byte{7}, make([]byte, 32)...)
+		err := decode(data, 0)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrUnexpectedCompression)
+		assert.Contains(t, err.Error(), "unexpected block info field 7")
+	})
+}
+
+func TestBlockNestedLowCardinality(t *testing.T) {
+	testCases := []struct {
+		chType string
+		rows   []any
+	}{
+		{"Array(LowCardinality(String))", []any{[]string{"a", "b"}, []string{}, []string{"b"}}},
+		{"Array(LowCardinality(Nullable(String)))", []any{[]*string{nil}, []*string{}}},
+		{"Map(LowCardinality(String), UInt64)", []any{map[string]uint64{"a": 1}, map[string]uint64{"b": 2, "c": 3}}},
+		{"Tuple(LowCardinality(String), UInt8)", []any{[]any{"a", uint8(1)}, []any{"b", uint8(2)}}},
+		{"SimpleAggregateFunction(anyLast, LowCardinality(String))", []any{"a", "b"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.chType, func(t *testing.T) {
+			block := &Block{}
+			require.NoError(t, block.AddColumn("tags", column.Type(tc.chType)))
+			for _, row := range tc.rows {
+				require.NoError(t, block.Append(row))
+			}
+			var buf proto.Buffer
+			require.NoError(t, block.Encode(&buf, DBMS_TCP_PROTOCOL_VERSION))
+
+			// the key version of the dictionary comes right after the column header, ahead of any array offsets
+			var head proto.Buffer
+			encodeBlockInfo(&head)
+			head.PutUVarInt(1)
+			head.PutUVarInt(uint64(len(tc.rows)))
+			head.PutString("tags")
+			head.PutString(tc.chType)
+			head.PutBool(false)
+			require.True(t, bytes.HasPrefix(buf.Buf, head.Buf))
+			assert.Equal(t, []byte{1, 0, 0, 0, 0, 0, 0, 0}, buf.Buf[len(head.Buf):len(head.Buf)+8])
+
+			decoded := &Block{}
+			reader := bytes.NewReader(buf.Buf)
+			require.NoError(t, decoded.Decode(proto.NewReader(reader), DBMS_TCP_PROTOCOL_VERSION))
+			assert.Zero(t, reader.Len(), "read to the end")
+			require.Equal(t, len(tc.rows), decoded.Rows())
+			for i := range tc.rows {
+				assert.Equal(t, block.Columns[0].Row(i, false), decoded.Columns[0].Row(i, false), "row %d", i)
+			}
+		})
+	}
+}
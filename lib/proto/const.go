@@ -22,6 +22,7 @@ const (
 	DBMS_MIN_REVISION_WITH_CLIENT_INFO                          = 54032
 	DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE                      = 54058
 	DBMS_MIN_REVISION_WITH_QUOTA_KEY_IN_CLIENT_INFO             = 54060
+	DBMS_MIN_REVISION_WITH_TABLES_STATUS                        = 54226
 	DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME                  = 54372
 	DBMS_MIN_REVISION_WITH_VERSION_PATCH                        = 54401
 	DBMS_MIN_REVISION_WITH_CLIENT_WRITE_INFO                    = 54420
@@ -41,11 +42,12 @@ const (
 )
 
 const (
-	ClientHello  = 0
-	ClientQuery  = 1
-	ClientData   = 2
-	ClientCancel = 3
-	ClientPing   = 4
+	ClientHello               = 0
+	ClientQuery               = 1
+	ClientData                = 2
+	ClientCancel              = 3
+	ClientPing                = 4
+	ClientTablesStatusRequest = 5
 )
 
 const (
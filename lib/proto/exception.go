@@ -37,6 +37,26 @@ func (e *Exception) Error() string {
 	return fmt.Sprintf("code: %d, message: %s", e.Code, e.Message)
 }
 
+// Verbose returns the exception with its name and the server stack trace, followed by
+// any nested exceptions. Error stays terse so it can be logged in production.
+func (e *Exception) Verbose() string {
+	var b strings.Builder
+	e.verbose(&b)
+	for i := range e.Nested {
+		b.WriteString("\n\ncaused by: ")
+		e.Nested[i].verbose(&b)
+	}
+	return b.String()
+}
+
+func (e *Exception) verbose(b *strings.Builder) {
+	fmt.Fprintf(b, "code: %d, name: %s, message: %s", e.Code, e.Name, e.Message)
+	if e.StackTrace != "" {
+		b.WriteString("\nstack trace:\n")
+		b.WriteString(strings.TrimRight(e.StackTrace, "\n"))
+	}
+}
+
 func (e *Exception) Decode(reader *proto.Reader) (err error) {
 	var exceptions []Exception
 	for {
@@ -64,14 +84,14 @@ func (e *Exception) decode(reader *proto.Reader) (err error) {
 	if e.Code, err = reader.Int32(); err != nil {
 		return err
 	}
-	if e.Name, err = reader.Str(); err != nil {
+	if e.Name, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 		return err
 	}
-	if e.Message, err = reader.Str(); err != nil {
+	if e.Message, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 		return err
 	}
 	e.Message = strings.TrimSpace(strings.TrimPrefix(e.Message, e.Name+":"))
-	if e.StackTrace, err = reader.Str(); err != nil {
+	if e.StackTrace, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 		return err
 	}
 	if e.nested, err = reader.Bool(); err != nil {
@@ -0,0 +1,61 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExceptionStackTrace(t *testing.T) {
+	var buf proto.Buffer
+	for _, e := range []struct {
+		code          int32
+		name, message string
+		stack         string
+		nested        bool
+	}{
+		{60, "DB::Exception", "DB::Exception: Table default.t doesn't exist", "0. DB::Exception::Exception()\n1. DB::Context::getTable()\n", true},
+		{1000, "Poco::Exception", "Poco::Exception: I/O error", "0. Poco::IOException::IOException()\n", false},
+	} {
+		buf.PutInt32(e.code)
+		buf.PutString(e.name)
+		buf.PutString(e.message)
+		buf.PutString(e.stack)
+		buf.PutBool(e.nested)
+	}
+
+	var e Exception
+	require.NoError(t, e.Decode(proto.NewReader(bytes.NewReader(buf.Buf))))
+	assert.Equal(t, "code: 60, message: Table default.t doesn't exist", e.Error())
+	assert.Equal(t, "0. DB::Exception::Exception()\n1. DB::Context::getTable()\n", e.StackTrace)
+	require.Len(t, e.Nested, 1)
+	assert.Equal(t, "0. Poco::IOException::IOException()\n", e.Nested[0].StackTrace)
+	assert.Equal(t, `code: 60, name: DB::Exception, message: Table default.t doesn't exist
+stack trace:
+0. DB::Exception::Exception()
+1. DB::Context::getTable()
+
+caused by: code: 1000, name: Poco::Exception, message: I/O error
+stack trace:
+0. Poco::IOException::IOException()`, e.Verbose())
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	chproto "github.com/ClickHouse/ch-go/proto"
 	"gopkg.in/yaml.v3"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -50,8 +51,17 @@ type ServerHandshake struct {
 	Name        string
 	DisplayName string
 	Revision    uint64
-	Version     Version
-	Timezone    *time.Location
+	// NegotiatedRevision is the protocol revision used by the connection, the lower of the
+	// client and server revisions. It's the one to check DBMS_MIN_REVISION_WITH_* against.
+	NegotiatedRevision uint64
+	Version            Version
+	Timezone           *time.Location
+	// TimezoneName is the timezone as sent by the server. Timezone is UTC when no time zone
+	// data is available for it.
+	TimezoneName string
+	// Addr is the Options.Addr entry the connection was dialed to, after failover. It's set by
+	// the client, the server doesn't send it.
+	Addr string
 }
 
 type Version struct {
@@ -85,8 +95,16 @@ func CheckMinVersion(constraint Version, version Version) bool {
 	return true
 }
 
-func (srv *ServerHandshake) Decode(reader *chproto.Reader) (err error) {
-	if srv.Name, err = reader.Str(); err != nil {
+// Decode reads the server hello sent to a client at least as recent as the server, see
+// DecodeRevision for clients advertising an older revision.
+func (srv *ServerHandshake) Decode(reader *chproto.Reader) error {
+	return srv.DecodeRevision(reader, math.MaxUint64)
+}
+
+// DecodeRevision reads the server hello sent to a client advertising revision. The server only
+// sends the fields known to the client, so they are gated on the negotiated revision.
+func (srv *ServerHandshake) DecodeRevision(reader *chproto.Reader, revision uint64) (err error) {
+	if srv.Name, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 		return fmt.Errorf("could not read server name: %v", err)
 	}
 	if srv.Version.Major, err = reader.UVarInt(); err != nil {
@@ -98,21 +116,22 @@ func (srv *ServerHandshake) Decode(reader *chproto.Reader) (err error) {
 	if srv.Revision, err = reader.UVarInt(); err != nil {
 		return fmt.Errorf("could not read server revision: %v", err)
 	}
-	if srv.Revision >= DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE {
-		name, err := reader.Str()
-		if err != nil {
+	srv.NegotiatedRevision = min(srv.Revision, revision)
+	if srv.NegotiatedRevision >= DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE {
+		if srv.TimezoneName, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 			return fmt.Errorf("could not read server timezone: %v", err)
 		}
-		if srv.Timezone, err = timezone.Load(name); err != nil {
-			return fmt.Errorf("could not load time location: %v", err)
+		if srv.Timezone, err = timezone.Load(srv.TimezoneName); err != nil {
+			// a timezone the local tzdata doesn't know is no reason to refuse the connection
+			srv.Timezone = time.UTC
 		}
 	}
-	if srv.Revision >= DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME {
-		if srv.DisplayName, err = reader.Str(); err != nil {
+	if srv.NegotiatedRevision >= DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME {
+		if srv.DisplayName, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 			return fmt.Errorf("could not read server display name: %v", err)
 		}
 	}
-	if srv.Revision >= DBMS_MIN_REVISION_WITH_VERSION_PATCH {
+	if srv.NegotiatedRevision >= DBMS_MIN_REVISION_WITH_VERSION_PATCH {
 		if srv.Version.Patch, err = reader.UVarInt(); err != nil {
 			return fmt.Errorf("could not read server patch: %v", err)
 		}
@@ -122,6 +141,15 @@ func (srv *ServerHandshake) Decode(reader *chproto.Reader) (err error) {
 	return nil
 }
 
+// Location returns the server timezone the connection negotiated, UTC when the server
+// didn't send one.
+func (srv ServerHandshake) Location() *time.Location {
+	if srv.Timezone == nil {
+		return time.UTC
+	}
+	return srv.Timezone
+}
+
 func (srv ServerHandshake) String() string {
 	return fmt.Sprintf("%s (%s) server version %d.%d.%d revision %d (timezone %s)", srv.Name, srv.DisplayName,
 		srv.Version.Major,
@@ -0,0 +1,64 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHandshakeDecode(t *testing.T) {
+	// the server sends its own revision and the fields the negotiated revision has
+	hello := func(revision, negotiated uint64) []byte {
+		var buf proto.Buffer
+		buf.PutString("ClickHouse")
+		buf.PutUVarInt(24)
+		buf.PutUVarInt(3)
+		buf.PutUVarInt(revision)
+		if negotiated >= DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE {
+			buf.PutString("Europe/Berlin")
+		}
+		if negotiated >= DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME {
+			buf.PutString("replica-1")
+		}
+		if negotiated >= DBMS_MIN_REVISION_WITH_VERSION_PATCH {
+			buf.PutUVarInt(5)
+		}
+		return buf.Buf
+	}
+
+	var server ServerHandshake
+	require.NoError(t, server.Decode(proto.NewReader(bytes.NewReader(hello(DBMS_TCP_PROTOCOL_VERSION, DBMS_TCP_PROTOCOL_VERSION)))))
+	assert.Equal(t, "replica-1", server.DisplayName)
+	assert.Equal(t, "Europe/Berlin", server.TimezoneName)
+	assert.Equal(t, Version{24, 3, 5}, server.Version)
+	assert.EqualValues(t, DBMS_TCP_PROTOCOL_VERSION, server.NegotiatedRevision)
+
+	// a client advertising an older revision is sent the fields of that revision only
+	var older ServerHandshake
+	reader := proto.NewReader(bytes.NewReader(hello(DBMS_TCP_PROTOCOL_VERSION, DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME-1)))
+	require.NoError(t, older.DecodeRevision(reader, DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME-1))
+	assert.Empty(t, older.DisplayName)
+	assert.Equal(t, "Europe/Berlin", older.TimezoneName)
+	assert.EqualValues(t, DBMS_TCP_PROTOCOL_VERSION, older.Revision)
+	assert.EqualValues(t, DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME-1, older.NegotiatedRevision)
+}
@@ -164,20 +164,9 @@ func (s Settings) Encode(buffer *chproto.Buffer, revision uint64) error {
 
 func (s *Setting) encode(buffer *chproto.Buffer, revision uint64) error {
 	buffer.PutString(s.Key)
-	if revision <= DBMS_MIN_REVISION_WITH_SETTINGS_SERIALIZED_AS_STRINGS {
-		var value uint64
-		switch v := s.Value.(type) {
-		case int:
-			value = uint64(v)
-		case bool:
-			if value = 0; v {
-				value = 1
-			}
-		default:
-			return fmt.Errorf("query setting %s has unsupported data type", s.Key)
-		}
-		buffer.PutUVarInt(value)
-		return nil
+	if revision < DBMS_MIN_REVISION_WITH_SETTINGS_SERIALIZED_AS_STRINGS {
+		// older servers read each value in the binary format of the setting type and have no flags
+		return s.encodeBinary(buffer)
 	}
 
 	{
@@ -205,6 +194,26 @@ func (s *Setting) encode(buffer *chproto.Buffer, revision uint64) error {
 	return nil
 }
 
+func (s *Setting) encodeBinary(buffer *chproto.Buffer) error {
+	switch v := s.Value.(type) {
+	case int:
+		buffer.PutUVarInt(uint64(v))
+	case uint64:
+		buffer.PutUVarInt(v)
+	case bool:
+		var value uint64
+		if v {
+			value = 1
+		}
+		buffer.PutUVarInt(value)
+	case string:
+		buffer.PutString(v)
+	default:
+		return fmt.Errorf("query setting %s has unsupported data type", s.Key)
+	}
+	return nil
+}
+
 type Parameters []Parameter
 
 type Parameter struct {
@@ -235,13 +244,15 @@ func (s *Parameter) encode(buffer *chproto.Buffer, revision uint64) error {
 	return nil
 }
 
+var fieldDumpReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
 // encodes a field dump with an appropriate type format
 // implements the same logic as in ClickHouse Field::restoreFromDump (https://github.com/ClickHouse/ClickHouse/blob/master/src/Core/Field.cpp#L312)
 // currently, only string type is supported
 func encodeFieldDump(value any) (string, error) {
 	switch v := value.(type) {
 	case string:
-		return fmt.Sprintf("'%v'", strings.ReplaceAll(v, "'", "\\'")), nil
+		return "'" + fieldDumpReplacer.Replace(v) + "'", nil
 	}
 
 	return "", fmt.Errorf("unsupported field type %T", value)
@@ -0,0 +1,69 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsEncodeRevision(t *testing.T) {
+	settings := Settings{
+		{Key: "max_threads", Value: 4},
+		{Key: "readonly", Value: true, Important: true},
+	}
+
+	testCases := []struct {
+		name     string
+		revision uint64
+		expected []byte
+	}{
+		{
+			name:     "binary",
+			revision: DBMS_MIN_REVISION_WITH_SETTINGS_SERIALIZED_AS_STRINGS - 1,
+			expected: []byte("\x0bmax_threads\x04\x08readonly\x01"),
+		},
+		{
+			name:     "strings with flags",
+			revision: DBMS_MIN_REVISION_WITH_SETTINGS_SERIALIZED_AS_STRINGS,
+			expected: []byte("\x0bmax_threads\x00\x014\x08readonly\x01\x04true"),
+		},
+		{
+			name:     "current",
+			revision: DBMS_TCP_PROTOCOL_VERSION,
+			expected: []byte("\x0bmax_threads\x00\x014\x08readonly\x01\x04true"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf proto.Buffer
+			require.NoError(t, settings.Encode(&buf, tc.revision))
+			assert.Equal(t, tc.expected, buf.Buf)
+		})
+	}
+
+	t.Run("unsupported binary value", func(t *testing.T) {
+		var buf proto.Buffer
+		err := Settings{{Key: "ratio", Value: 0.5}}.Encode(&buf, DBMS_MIN_REVISION_WITH_SETTINGS_SERIALIZED_AS_STRINGS-1)
+		assert.EqualError(t, err, "query setting ratio has unsupported data type")
+	})
+}
@@ -0,0 +1,53 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"fmt"
+
+	chproto "github.com/ClickHouse/ch-go/proto"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+)
+
+// DefaultMaxStringSize is the largest string accepted from the server unless configured otherwise.
+const DefaultMaxStringSize = 256 << 20 // 256MiB
+
+// ErrStringTooLarge is returned for a string read from the server longer than the limit, be it a
+// name or a value of a String column.
+var ErrStringTooLarge = column.ErrStringTooLarge
+
+// ReadString reads a length prefixed string, refusing to allocate more than max bytes
+// so a corrupted or malicious length prefix can't exhaust memory. A max of zero or less
+// means DefaultMaxStringSize.
+func ReadString(reader *chproto.Reader, max int) (string, error) {
+	if max <= 0 {
+		max = DefaultMaxStringSize
+	}
+	n, err := reader.StrLen()
+	if err != nil {
+		return "", err
+	}
+	if n > max {
+		return "", fmt.Errorf("%w: length %d, limit %d", ErrStringTooLarge, n, max)
+	}
+	raw, err := reader.ReadRaw(n)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
@@ -28,10 +28,10 @@ type TableColumns struct {
 }
 
 func (t *TableColumns) Decode(reader *chproto.Reader, revision uint64) (err error) {
-	if t.First, err = reader.Str(); err != nil {
+	if t.First, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 		return err
 	}
-	if t.Second, err = reader.Str(); err != nil {
+	if t.Second, err = ReadString(reader, DefaultMaxStringSize); err != nil {
 		return err
 	}
 	return nil
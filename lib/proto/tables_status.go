@@ -0,0 +1,103 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"fmt"
+
+	chproto "github.com/ClickHouse/ch-go/proto"
+)
+
+// maxTablesStatusTables bounds the tables of a status request and so the tables of the response
+// read back, the server answers for each table asked about.
+const maxTablesStatusTables = 10000
+
+type TableName struct {
+	Database string
+	Table    string
+}
+
+func (t TableName) String() string {
+	return t.Database + "." + t.Table
+}
+
+// TableStatus is the replication state of a table, AbsoluteDelay is the replica lag in seconds.
+type TableStatus struct {
+	IsReplicated  bool
+	AbsoluteDelay uint32
+}
+
+type TablesStatusRequest struct {
+	Tables []TableName
+}
+
+func (r *TablesStatusRequest) Encode(buffer *chproto.Buffer, revision uint64) error {
+	if revision < DBMS_MIN_REVISION_WITH_TABLES_STATUS {
+		return fmt.Errorf("tables status request requires server revision %d, got %d", DBMS_MIN_REVISION_WITH_TABLES_STATUS, revision)
+	}
+	if len(r.Tables) > maxTablesStatusTables {
+		return fmt.Errorf("too many tables in tables status request: %d, at most %d", len(r.Tables), maxTablesStatusTables)
+	}
+	buffer.PutByte(ClientTablesStatusRequest)
+	buffer.PutUVarInt(uint64(len(r.Tables)))
+	for _, t := range r.Tables {
+		buffer.PutString(t.Database)
+		buffer.PutString(t.Table)
+	}
+	return nil
+}
+
+type TablesStatusResponse struct {
+	Tables map[TableName]TableStatus
+}
+
+func (r *TablesStatusResponse) Decode(reader *chproto.Reader) error {
+	size, err := reader.UVarInt()
+	if err != nil {
+		return err
+	}
+	if size > maxTablesStatusTables {
+		return fmt.Errorf("too large tables status response: %d tables", size)
+	}
+	// the map grows with the tables actually read rather than the count claimed
+	r.Tables = make(map[TableName]TableStatus)
+	for i := uint64(0); i < size; i++ {
+		var (
+			name   TableName
+			status TableStatus
+		)
+		if name.Database, err = ReadString(reader, DefaultMaxStringSize); err != nil {
+			return err
+		}
+		if name.Table, err = ReadString(reader, DefaultMaxStringSize); err != nil {
+			return err
+		}
+		if status.IsReplicated, err = reader.Bool(); err != nil {
+			return err
+		}
+		if status.IsReplicated {
+			delay, err := reader.UVarInt()
+			if err != nil {
+				return err
+			}
+			status.AbsoluteDelay = uint32(delay)
+		}
+		r.Tables[name] = status
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTablesStatusRequestEncode(t *testing.T) {
+	request := TablesStatusRequest{Tables: []TableName{{"db", "events"}, {"db", "users"}}}
+
+	var buf proto.Buffer
+	require.NoError(t, request.Encode(&buf, DBMS_TCP_PROTOCOL_VERSION))
+	assert.Equal(t, []byte("\x05\x02\x02db\x06events\x02db\x05users"), buf.Buf)
+
+	buf.Reset()
+	assert.Error(t, request.Encode(&buf, DBMS_MIN_REVISION_WITH_TABLES_STATUS-1))
+	assert.Empty(t, buf.Buf)
+
+	request.Tables = make([]TableName, maxTablesStatusTables+1)
+	assert.Error(t, request.Encode(&buf, DBMS_TCP_PROTOCOL_VERSION))
+	assert.Empty(t, buf.Buf)
+}
+
+func TestTablesStatusResponseDecode(t *testing.T) {
+	var buf proto.Buffer
+	buf.PutUVarInt(2)
+	buf.PutString("db")
+	buf.PutString("events")
+	buf.PutBool(true)
+	buf.PutUVarInt(300)
+	buf.PutString("db")
+	buf.PutString("users")
+	buf.PutBool(false)
+
+	var response TablesStatusResponse
+	require.NoError(t, response.Decode(proto.NewReader(bytes.NewReader(buf.Buf))))
+	assert.Equal(t, map[TableName]TableStatus{
+		{"db", "events"}: {IsReplicated: true, AbsoluteDelay: 300},
+		{"db", "users"}:  {},
+	}, response.Tables)
+
+	for n := 0; n < len(buf.Buf); n++ {
+		var truncated TablesStatusResponse
+		assert.Error(t, truncated.Decode(proto.NewReader(bytes.NewReader(buf.Buf[:n]))), "truncated to %d bytes", n)
+	}
+
+	buf.Reset()
+	buf.PutUVarInt(maxTablesStatusTables + 1)
+	var huge TablesStatusResponse
+	assert.ErrorContains(t, huge.Decode(proto.NewReader(bytes.NewReader(buf.Buf))), "too large tables status response")
+	assert.Nil(t, huge.Tables)
+}
@@ -0,0 +1,89 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package proto
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The packets are decoded with the varint (LEB128) reader of ch-go, these make sure a corrupt
+// stream fails with an error instead of being truncated or read past.
+func TestUVarInt(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value uint64
+		wire  []byte
+	}{
+		{"zero", 0, []byte{0x00}},
+		{"largest single byte", 127, []byte{0x7f}},
+		{"smallest two bytes", 128, []byte{0x80, 0x01}},
+		{"max uint64", math.MaxUint64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buffer proto.Buffer
+			buffer.PutUVarInt(tc.value)
+			assert.Equal(t, tc.wire, buffer.Buf)
+
+			value, err := proto.NewReader(bytes.NewReader(append(tc.wire, 0x2a))).UVarInt()
+			require.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+		})
+	}
+}
+
+// endless is a stream of continuation bytes that never ends.
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0x80
+	}
+	return len(p), nil
+}
+
+func TestUVarIntMalformed(t *testing.T) {
+	testCases := []struct {
+		name   string
+		reader io.Reader
+	}{
+		{"overlong", bytes.NewReader(bytes.Repeat([]byte{0x80}, 11))},
+		{"endless continuation", endless{}},
+		{"exceeds 64 bits", bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02})},
+		{"truncated", bytes.NewReader([]byte{0xff, 0xff})},
+		{"empty", bytes.NewReader(nil)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := proto.NewReader(tc.reader).UVarInt()
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("corrupt packet", func(t *testing.T) {
+		var progress Progress
+		assert.Error(t, progress.Decode(proto.NewReader(endless{}), 0))
+	})
+}
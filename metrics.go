@@ -0,0 +1,146 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+)
+
+// QueryStats describes a finished query, see MetricsHook.
+type QueryStats struct {
+	Query         string
+	QueryID       string // set with WithQueryID, over HTTP the one the server generated otherwise
+	Addr          string // the Options.Addr entry of the connection that ran the query
+	Duration      time.Duration
+	BytesSent     uint64 // written to the connection, as sent on the wire, native protocol only
+	BytesReceived uint64 // read from the connection, as sent on the wire, native protocol only
+	RowsReturned  uint64 // rows of the data blocks returned by the server
+	ReadRows      uint64 // rows read by the server, accumulated from progress packets, native protocol only
+	ReadBytes     uint64 // bytes read by the server, accumulated from progress packets, native protocol only
+	WroteRows     uint64 // rows written by the server, accumulated from progress packets, native protocol only
+	Err           error
+}
+
+// MetricsHook receives per query events of Query, QueryRow, Select and Exec, it's registered with
+// Options.Metrics. Calls happen on the goroutine running the query and must not block.
+type MetricsHook interface {
+	QueryStart(ctx context.Context, query string)
+	QueryEnd(ctx context.Context, stats QueryStats)
+}
+
+// queryMetrics collects the QueryStats of a single query. A nil *queryMetrics is valid and
+// does nothing, which is what connections without a MetricsHook use.
+type queryMetrics struct {
+	ctx      context.Context
+	hook     MetricsHook
+	conn     *connect // nil over HTTP, which doesn't count bytes
+	start    time.Time
+	sent     uint64
+	received uint64
+	stats    QueryStats
+}
+
+func (c *connect) startQueryMetrics(ctx context.Context, query, queryID string) *queryMetrics {
+	if c.opt.Metrics == nil {
+		return nil
+	}
+	c.opt.Metrics.QueryStart(ctx, query)
+	return &queryMetrics{
+		ctx:      ctx,
+		hook:     c.opt.Metrics,
+		conn:     c,
+		start:    time.Now(),
+		sent:     c.bytesSent,
+		received: c.bytesReceived,
+		stats:    QueryStats{Query: query, QueryID: queryID, Addr: c.addr},
+	}
+}
+
+func (h *httpConnect) startQueryMetrics(ctx context.Context, query, queryID string) *queryMetrics {
+	if h.metrics == nil {
+		return nil
+	}
+	h.metrics.QueryStart(ctx, query)
+	return &queryMetrics{
+		ctx:   ctx,
+		hook:  h.metrics,
+		start: time.Now(),
+		stats: QueryStats{Query: query, QueryID: queryID, Addr: h.addr},
+	}
+}
+
+// response takes the query id from the response of the HTTP interface when none was set.
+func (m *queryMetrics) response(res *http.Response) {
+	if m == nil || m.stats.QueryID != "" {
+		return
+	}
+	m.stats.QueryID = res.Header.Get("X-ClickHouse-Query-Id")
+}
+
+// observe makes the metrics account for the progress packets handled by on.
+func (m *queryMetrics) observe(on *onProcess) {
+	if m == nil {
+		return
+	}
+	progress := on.progress
+	on.progress = func(p *Progress) {
+		m.stats.ReadRows += p.Rows
+		m.stats.ReadBytes += p.Bytes
+		m.stats.WroteRows += p.WroteRows
+		if progress != nil {
+			progress(p)
+		}
+	}
+}
+
+func (m *queryMetrics) block(b *proto.Block) {
+	if m == nil {
+		return
+	}
+	m.stats.RowsReturned += uint64(b.Rows())
+}
+
+func (m *queryMetrics) end(err error) {
+	if m == nil {
+		return
+	}
+	m.stats.Duration = time.Since(m.start)
+	if m.conn != nil {
+		m.stats.BytesSent = m.conn.bytesSent - m.sent
+		m.stats.BytesReceived = m.conn.bytesReceived - m.received
+	}
+	m.stats.Err = err
+	m.hook.QueryEnd(m.ctx, m.stats)
+}
+
+// countingReader counts the bytes read from the server.
+type countingReader struct {
+	reader io.Reader
+	n      *uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	*r.n += uint64(n)
+	return n, err
+}
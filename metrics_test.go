@@ -0,0 +1,168 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	mu      sync.Mutex
+	started []string
+	ended   []QueryStats
+}
+
+func (h *recordingHook) QueryStart(ctx context.Context, query string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = append(h.started, query)
+}
+
+func (h *recordingHook) QueryEnd(ctx context.Context, stats QueryStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ended = append(h.ended, stats)
+}
+
+func TestMetricsHook(t *testing.T) {
+	t.Run("query", func(t *testing.T) {
+		var (
+			hook = &recordingHook{}
+			srv  = newFakeServer().blocks(t, 3, 10)
+		)
+		conn, err := Open(&Options{DialContext: srv.dial, Metrics: hook})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+		require.NoError(t, err)
+		for rows.Next() {
+		}
+		require.NoError(t, rows.Close())
+
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		assert.Equal(t, []string{"SELECT id, name, code FROM t"}, hook.started)
+		require.Len(t, hook.ended, 1)
+		stats := hook.ended[0]
+		assert.Equal(t, "SELECT id, name, code FROM t", stats.Query)
+		assert.Empty(t, stats.QueryID)
+		assert.EqualValues(t, 30, stats.RowsReturned)
+		assert.NotZero(t, stats.BytesSent)
+		assert.Greater(t, stats.BytesReceived, uint64(30*8))
+		assert.NotZero(t, stats.Duration)
+		assert.NoError(t, stats.Err)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		var (
+			hook = &recordingHook{}
+			srv  = newFakeServer().progress(5, 5).progress(3, 3).raise(241, "Memory limit exceeded")
+		)
+		conn, err := Open(&Options{DialContext: srv.dial, Metrics: hook})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		err = conn.Exec(Context(context.Background(), WithQueryID("insert-1")), "INSERT INTO t SELECT * FROM s")
+		require.Error(t, err)
+
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		require.Len(t, hook.ended, 1)
+		stats := hook.ended[0]
+		assert.Equal(t, "INSERT INTO t SELECT * FROM s", stats.Query)
+		assert.Equal(t, "insert-1", stats.QueryID)
+		assert.EqualValues(t, 8, stats.ReadRows)
+		assert.EqualValues(t, 8, stats.WroteRows)
+		assert.Zero(t, stats.RowsReturned)
+		assert.Equal(t, err, stats.Err)
+	})
+
+	t.Run("address after failover", func(t *testing.T) {
+		var (
+			hook = &recordingHook{}
+			srv  = newFakeServer().progress(1, 1).endOfStream()
+			dial = func(ctx context.Context, addr string) (net.Conn, error) {
+				if addr == "replica-1:9000" {
+					return nil, errors.New("connection refused")
+				}
+				return srv.dial(ctx, addr)
+			}
+		)
+		conn, err := Open(&Options{
+			Addr:        []string{"replica-1:9000", "replica-2:9000"},
+			DialContext: dial,
+			Metrics:     hook,
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.Exec(context.Background(), "INSERT INTO t SELECT * FROM s"))
+
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		require.Len(t, hook.ended, 1)
+		assert.Equal(t, "replica-2:9000", hook.ended[0].Addr)
+
+		version, err := conn.ServerVersion()
+		require.NoError(t, err)
+		assert.Equal(t, "replica-2:9000", version.Addr)
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		hook := &recordingHook{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-ClickHouse-Query-Id", "generated-1")
+			(&fakeHTTPServer{}).ServeHTTP(w, r)
+		}))
+		defer ts.Close()
+		addr := strings.TrimPrefix(ts.URL, "http://")
+		conn, err := Open(&Options{Addr: []string{addr}, Protocol: HTTP, Metrics: hook})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var one uint8
+		require.NoError(t, conn.QueryRow(context.Background(), "SELECT 1").Scan(&one))
+		err = conn.Exec(Context(context.Background(), WithQueryID("drop-1")), "DROP TABLE t")
+		require.Error(t, err)
+
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		assert.Equal(t, []string{"SELECT 1", "DROP TABLE t"}, hook.started, "the time zone and version queries of the driver aren't reported")
+		require.Len(t, hook.ended, 2)
+		query := hook.ended[0]
+		assert.Equal(t, "SELECT 1", query.Query)
+		assert.Equal(t, "generated-1", query.QueryID, "the query id the server generated")
+		assert.Equal(t, addr, query.Addr)
+		assert.EqualValues(t, 1, query.RowsReturned)
+		assert.NoError(t, query.Err)
+		exec := hook.ended[1]
+		assert.Equal(t, "drop-1", exec.QueryID)
+		assert.Equal(t, err, exec.Err)
+	})
+}
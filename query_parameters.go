@@ -18,14 +18,19 @@
 package clickhouse
 
 import (
-	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
-	"github.com/pkg/errors"
+	"fmt"
+	"reflect"
 	"regexp"
 	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/pkg/errors"
 )
 
 var (
+	// Deprecated: named query parameters accept any value that can be formatted as a literal of the declared type.
 	ErrExpectedStringValueInNamedValueForQueryParameter = errors.New("expected string value in NamedValue for query parameter")
+	ErrExpectedNamedValueForQueryParameter              = errors.New("expected NamedValue for query parameter")
 
 	hasQueryParamsRe = regexp.MustCompile("{.+:.+}")
 )
@@ -43,14 +48,15 @@ func bindQueryOrAppendParameters(paramsProtocolSupport bool, options *QueryOptio
 		hasQueryParamsRe.MatchString(query) {
 		options.parameters = make(Parameters, len(args))
 		for _, a := range args {
-			if p, ok := a.(driver.NamedValue); ok {
-				if str, ok := p.Value.(string); ok {
-					options.parameters[p.Name] = str
-					continue
-				}
+			p, ok := a.(driver.NamedValue)
+			if !ok {
+				return "", ErrExpectedNamedValueForQueryParameter
 			}
-
-			return "", ErrExpectedStringValueInNamedValueForQueryParameter
+			value, err := formatParameter(timezone, p.Value)
+			if err != nil {
+				return "", fmt.Errorf("query parameter %s: %w", p.Name, err)
+			}
+			options.parameters[p.Name] = value
 		}
 
 		return query, nil
@@ -58,3 +64,24 @@ func bindQueryOrAppendParameters(paramsProtocolSupport bool, options *QueryOptio
 
 	return bind(timezone, query, args...)
 }
+
+// formatParameter renders a value the way the server parses the text of a query parameter:
+// strings are sent as they are, NULL is written in the escaped form and everything else uses
+// the same literal syntax as bind.
+func formatParameter(tz *time.Location, v any) (string, error) {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return `\N`, nil
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		if tz != nil {
+			v = v.In(tz)
+		}
+		return v.Format("2006-01-02 15:04:05.999999999"), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return format(tz, Seconds, v)
+}
@@ -0,0 +1,77 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindQueryParameters(t *testing.T) {
+	var nilPtr *int64
+	testCases := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"string", "O'Brien", "O'Brien"},
+		{"int64", int64(-42), "-42"},
+		{"uint8", uint8(7), "7"},
+		{"float64", 1.5, "1.5"},
+		{"bool", true, "1"},
+		{"nil", nil, `\N`},
+		{"nil pointer", nilPtr, `\N`},
+		{"time", time.Date(2024, 3, 1, 12, 30, 0, 500_000_000, time.UTC), "2024-03-01 12:30:00.5"},
+		{"array", []string{"a", "b"}, "['a', 'b']"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var options QueryOptions
+			query, err := bindQueryOrAppendParameters(true, &options, "SELECT {v:String}", time.UTC, Named("v", tc.value))
+			require.NoError(t, err)
+			assert.Equal(t, "SELECT {v:String}", query)
+			assert.Equal(t, Parameters{"v": tc.expected}, options.parameters)
+		})
+	}
+
+	t.Run("positional arg", func(t *testing.T) {
+		var options QueryOptions
+		_, err := bindQueryOrAppendParameters(true, &options, "SELECT {v:Int64}", time.UTC, 42)
+		assert.ErrorIs(t, err, ErrExpectedNamedValueForQueryParameter)
+	})
+}
+
+func TestQueryParameters(t *testing.T) {
+	srv := newFakeServer().endOfStream()
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	const query = "SELECT * FROM t WHERE id = {id:Int64} AND name = {name:String}"
+	require.NoError(t, conn.Exec(context.Background(), query, Named("id", int64(42)), Named("name", `O'Brien\`)))
+
+	sent := string(srv.sent())
+	assert.Contains(t, sent, query)
+	assert.Contains(t, sent, "'42'")
+	assert.Contains(t, sent, `'O\'Brien\\'`)
+}
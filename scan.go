@@ -22,11 +22,21 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
 func (ch *clickhouse) Select(ctx context.Context, dest any, query string, args ...any) error {
+	return selectInto(dest, func() (driver.Rows, error) {
+		return ch.Query(ctx, query, args...)
+	})
+}
+
+// selectInto scans every row returned by query into the slice dest points to.
+func selectInto(dest any, query func() (driver.Rows, error)) error {
 	value := reflect.ValueOf(dest)
 	if value.Kind() != reflect.Ptr {
 		return &OpError{
@@ -51,7 +61,7 @@ func (ch *clickhouse) Select(ctx context.Context, dest any, query string, args .
 	}
 	var (
 		base      = direct.Type().Elem()
-		rows, err = ch.Query(ctx, query, args...)
+		rows, err = query()
 	)
 	if err != nil {
 		return err
@@ -70,7 +80,7 @@ func (ch *clickhouse) Select(ctx context.Context, dest any, query string, args .
 	return rows.Err()
 }
 
-func scan(block *proto.Block, row int, dest ...any) error {
+func scan(block *proto.Block, row int, timeLayout string, dest ...any) error {
 	columns := block.Columns
 	if len(columns) != len(dest) {
 		return &OpError{
@@ -79,6 +89,9 @@ func scan(block *proto.Block, row int, dest ...any) error {
 		}
 	}
 	for i, d := range dest {
+		if scanTimeString(columns[i], row-1, timeLayout, d) {
+			continue
+		}
 		if err := columns[i].ScanRow(d, row-1); err != nil {
 			return &OpError{
 				Err:        err,
@@ -88,3 +101,46 @@ func scan(block *proto.Block, row int, dest ...any) error {
 	}
 	return nil
 }
+
+var (
+	scanTypeTime    = reflect.TypeOf(time.Time{})
+	scanTypeTimePtr = reflect.TypeOf(&time.Time{})
+)
+
+// scanTimeString formats a date or time value scanned into a *string or **string with layout,
+// the columns themselves only scan into time.Time. It reports whether dest was handled.
+func scanTimeString(col column.Interface, row int, layout string, dest any) bool {
+	switch dest.(type) {
+	case *string, **string:
+	default:
+		return false
+	}
+	if t := col.ScanType(); t != scanTypeTime && t != scanTypeTimePtr {
+		return false
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	var value *time.Time
+	switch v := col.Row(row, true).(type) {
+	case *time.Time:
+		value = v
+	case time.Time:
+		value = &v
+	}
+	switch d := dest.(type) {
+	case *string:
+		if value == nil {
+			return false
+		}
+		*d = value.Format(layout)
+	case **string:
+		if value == nil {
+			*d = nil
+			return true
+		}
+		formatted := value.Format(layout)
+		*d = &formatted
+	}
+	return true
+}
@@ -91,14 +91,20 @@ func TestQueryParameters(t *testing.T) {
 		assert.Equal(t, uint64(100), actualNum)
 	})
 
-	t.Run("named args with only strings supported", func(t *testing.T) {
+	t.Run("named args with typed values", func(t *testing.T) {
+		var actualNum uint64
+		var actualStr string
 		row := client.QueryRow(
 			ctx,
 			"SELECT {num:UInt64}, {str:String}",
 			clickhouse.Named("num", 42),
 			clickhouse.Named("str", "hello"),
 		)
-		require.ErrorIs(t, row.Err(), clickhouse.ErrExpectedStringValueInNamedValueForQueryParameter)
+		require.NoError(t, row.Err())
+		require.NoError(t, row.Scan(&actualNum, &actualStr))
+
+		assert.Equal(t, uint64(42), actualNum)
+		assert.Equal(t, "hello", actualStr)
 	})
 
 	t.Run("unsupported arg type", func(t *testing.T) {
@@ -108,7 +114,7 @@ func TestQueryParameters(t *testing.T) {
 			1234,
 			"String",
 		)
-		require.ErrorIs(t, row.Err(), clickhouse.ErrExpectedStringValueInNamedValueForQueryParameter)
+		require.ErrorIs(t, row.Err(), clickhouse.ErrExpectedNamedValueForQueryParameter)
 	})
 
 	t.Run("with bind backwards compatibility", func(t *testing.T) {
@@ -0,0 +1,71 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package std
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	clickhouse_tests "github.com/ClickHouse/clickhouse-go/v2/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdColumnTypes(t *testing.T) {
+	dsns := map[string]clickhouse.Protocol{"Native": clickhouse.Native, "Http": clickhouse.HTTP}
+	useSSL, err := strconv.ParseBool(clickhouse_tests.GetEnv("CLICKHOUSE_USE_SSL", "false"))
+	require.NoError(t, err)
+	for name, protocol := range dsns {
+		t.Run(fmt.Sprintf("%s Protocol", name), func(t *testing.T) {
+			conn, err := GetStdDSNConnection(protocol, useSSL, nil)
+			require.NoError(t, err)
+			const query = `
+				SELECT
+					  CAST(1   AS UInt64)                    AS Col1
+					, CAST('X' AS String)                    AS Col2
+					, CAST(now() AS Nullable(DateTime))      AS Col3
+					, CAST([1, 2] AS Array(Int32))           AS Col4
+			`
+			rows, err := conn.Query(query)
+			require.NoError(t, err)
+			defer rows.Close()
+			types, err := rows.ColumnTypes()
+			require.NoError(t, err)
+			require.Len(t, types, 4)
+			expected := []struct {
+				name     string
+				chType   string
+				scanType reflect.Type
+			}{
+				{"Col1", "UInt64", reflect.TypeOf(uint64(0))},
+				{"Col2", "String", reflect.TypeOf("")},
+				{"Col3", "Nullable(DateTime)", reflect.TypeOf(&time.Time{})},
+				{"Col4", "Array(Int32)", reflect.TypeOf([]int32{})},
+			}
+			for i, v := range types {
+				assert.Equal(t, expected[i].name, v.Name())
+				assert.Equal(t, expected[i].chType, v.DatabaseTypeName())
+				assert.Equal(t, expected[i].scanType, v.ScanType())
+			}
+		})
+	}
+}
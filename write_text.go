@@ -0,0 +1,453 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/shopspring/decimal"
+)
+
+// TextFormat is the format WriteText writes rows in.
+type TextFormat int
+
+const (
+	TSV TextFormat = iota // TabSeparated
+	CSV
+)
+
+// WriteText writes the rows left in result to w the way the TabSeparated and CSV formats of
+// ClickHouse do, after a line of column names when header is set (TSVWithNames, CSVWithNames).
+// NULL is written as \N, arrays, tuples and maps the way ClickHouse writes them in text, e.g.
+// ['a','b'] or {'k':1}. Go maps don't keep the order of the server, so map keys are written
+// sorted, the fields of named tuples in the order of their type. result is closed once it's written.
+func WriteText(w io.Writer, result driver.Rows, format TextFormat, header bool) error {
+	defer result.Close()
+	r, ok := result.(*rows)
+	if !ok {
+		return &OpError{
+			Op:  "WriteText",
+			Err: fmt.Errorf("unsupported rows %T", result),
+		}
+	}
+	var (
+		out     = bufio.NewWriter(w)
+		text    = textWriter{format: format}
+		line    []byte
+		layouts []textLayout
+	)
+	if header {
+		for i, name := range r.Columns() {
+			line = text.appendString(text.appendDelimiter(line, i), name)
+		}
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	for r.Next() {
+		if layouts == nil {
+			// the columns are the same in every block
+			layouts = make([]textLayout, len(r.block.Columns))
+			for i, col := range r.block.Columns {
+				layouts[i] = newTextLayout(string(col.Type()))
+			}
+		}
+		line = line[:0]
+		for i, col := range r.block.Columns {
+			line = text.appendField(text.appendDelimiter(line, i), col.Row(r.row-1, false), layouts[i])
+		}
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+type textWriter struct {
+	format  TextFormat
+	scratch []byte
+}
+
+func (w *textWriter) appendDelimiter(buf []byte, i int) []byte {
+	switch {
+	case i == 0:
+		return buf
+	case w.format == CSV:
+		return append(buf, ',')
+	}
+	return append(buf, '\t')
+}
+
+func (w *textWriter) appendString(buf []byte, s string) []byte {
+	if w.format == CSV {
+		return appendCSVString(buf, s)
+	}
+	return appendEscaped(buf, s)
+}
+
+// appendField writes a top level value. Numbers are written as they are, strings are escaped in
+// TSV and quoted in CSV, as is the text of the other values in CSV.
+func (w *textWriter) appendField(buf []byte, v any, layout textLayout) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, `\N`...)
+	case string:
+		return w.appendString(buf, v)
+	case *string:
+		if v == nil {
+			return append(buf, `\N`...)
+		}
+		return w.appendString(buf, *v)
+	}
+	if value := reflect.ValueOf(v); value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return append(buf, `\N`...)
+		}
+		return w.appendField(buf, value.Elem().Interface(), layout)
+	}
+	if isTextNumber(v) {
+		return appendText(buf, v, layout, false)
+	}
+	w.scratch = appendText(w.scratch[:0], v, layout, false)
+	if w.format == CSV {
+		return appendCSVString(buf, string(w.scratch))
+	}
+	return append(buf, w.scratch...)
+}
+
+func isTextNumber(v any) bool {
+	switch v.(type) {
+	case bool, int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint,
+		float32, float64, decimal.Decimal, big.Int:
+		return true
+	}
+	return false
+}
+
+// appendText writes v the way ClickHouse writes it in text formats. Within arrays, tuples and
+// maps strings, dates, UUIDs and the like are quoted.
+func appendText(buf []byte, v any, layout textLayout, nested bool) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "NULL"...)
+	case string:
+		if nested {
+			return append(appendEscaped(append(buf, '\''), v), '\'')
+		}
+		return append(buf, v...)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case float32:
+		return appendTextFloat(buf, float64(v), 32)
+	case float64:
+		return appendTextFloat(buf, v, 64)
+	case decimal.Decimal:
+		return append(buf, v.String()...)
+	case big.Int:
+		return v.Append(buf, 10)
+	case *big.Int:
+		if v == nil {
+			return append(buf, "NULL"...)
+		}
+		return v.Append(buf, 10)
+	case time.Time:
+		if nested {
+			return append(v.AppendFormat(append(buf, '\''), layout.time), '\'')
+		}
+		return v.AppendFormat(buf, layout.time)
+	case *time.Time: // a Nullable, before it's taken for a fmt.Stringer
+		if v == nil {
+			return append(buf, "NULL"...)
+		}
+		return appendText(buf, *v, layout, nested)
+	case fmt.Stringer: // UUID, IPv4, IPv6
+		return appendText(buf, v.String(), layout, nested)
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return append(buf, "NULL"...)
+		}
+		return appendText(buf, value.Elem().Interface(), layout, nested)
+	case reflect.Slice, reflect.Array:
+		// unnamed tuples are scanned into []any and geo points into [2]float64
+		open, closing := byte('['), byte(']')
+		tuple := value.Kind() == reflect.Array || value.Type().Elem().Kind() == reflect.Interface
+		if tuple {
+			open, closing = '(', ')'
+		}
+		buf = append(buf, open)
+		for i := 0; i < value.Len(); i++ {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			elem := layout
+			if tuple {
+				elem = layout.element(i)
+			}
+			buf = appendText(buf, value.Index(i).Interface(), elem, true)
+		}
+		return append(buf, closing)
+	case reflect.Map:
+		return appendTextMap(buf, value, layout)
+	}
+	return fmt.Append(buf, v)
+}
+
+// appendTextMap writes a map with its keys sorted, a named tuple, scanned into a map[string]any,
+// is written as a tuple of its values in the order of the fields of its type.
+func appendTextMap(buf []byte, value reflect.Value, layout textLayout) []byte {
+	type entry struct {
+		key, value []byte
+		index      int // position of a named tuple field in its type, -1 when unknown
+	}
+	var (
+		tuple   = value.Type().Elem().Kind() == reflect.Interface
+		entries = make([]entry, 0, value.Len())
+		iter    = value.MapRange()
+	)
+	for iter.Next() {
+		e := entry{index: -1}
+		key, elem := layout.element(0), layout.element(1)
+		if tuple {
+			elem = layout.field(iter.Key().String())
+			if i, ok := layout.names[iter.Key().String()]; ok {
+				e.index = i
+			}
+		}
+		e.key = appendText(nil, iter.Key().Interface(), key, true)
+		e.value = appendText(nil, iter.Value().Interface(), elem, true)
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if a, b := entries[i].index, entries[j].index; a != b {
+			return b == -1 || (a != -1 && a < b)
+		}
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	open, closing := byte('{'), byte('}')
+	if tuple {
+		open, closing = '(', ')'
+	}
+	buf = append(buf, open)
+	for i, e := range entries {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if !tuple {
+			buf = append(append(buf, e.key...), ':')
+		}
+		buf = append(buf, e.value...)
+	}
+	return append(buf, closing)
+}
+
+// appendTextFloat writes the shortest representation of v, in exponent form below 1e-6 and
+// from 1e21 on like ClickHouse, e.g. 1e-7 and 1e21.
+func appendTextFloat(buf []byte, v float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(v):
+		return append(buf, "nan"...)
+	case math.IsInf(v, 1):
+		return append(buf, "inf"...)
+	case math.IsInf(v, -1):
+		return append(buf, "-inf"...)
+	}
+	if abs := math.Abs(v); abs == 0 || (abs >= 1e-6 && abs < 1e21) {
+		return strconv.AppendFloat(buf, v, 'f', -1, bitSize)
+	}
+	start := len(buf)
+	buf = strconv.AppendFloat(buf, v, 'e', -1, bitSize)
+	// 1e-07 and 1e+21 become 1e-7 and 1e21
+	e := start + bytes.IndexByte(buf[start:], 'e')
+	exponent, _ := strconv.Atoi(string(buf[e+1:]))
+	return strconv.AppendInt(buf[:e+1], int64(exponent), 10)
+}
+
+// textLayout holds the layouts of the date and time values of a column type, and of each element
+// of its tuples and maps, so that e.g. the Date of a Tuple(Date, DateTime) has no time part.
+type textLayout struct {
+	time     string
+	elements []textLayout   // of a tuple, or the key and value of a map
+	names    map[string]int // index of the elements of a named tuple
+}
+
+func newTextLayout(t string) textLayout {
+	t = strings.TrimSpace(t)
+	layout := textLayout{time: textTimeLayout(column.Type(t))}
+	open := strings.IndexByte(t, '(')
+	if open == -1 || !strings.HasSuffix(t, ")") {
+		return layout
+	}
+	params := splitTypeParams(t[open+1 : len(t)-1])
+	switch t[:open] {
+	case "Nullable", "LowCardinality", "Array":
+		return newTextLayout(params[0])
+	case "SimpleAggregateFunction":
+		return newTextLayout(params[len(params)-1])
+	case "Map":
+		for _, param := range params {
+			layout.elements = append(layout.elements, newTextLayout(param))
+		}
+	case "Tuple", "Nested":
+		layout.names = make(map[string]int, len(params))
+		for i, param := range params {
+			// named elements are written as "name Type", like the Tuple column parses them
+			if parts := strings.SplitN(param, " ", 2); len(parts) == 2 && !strings.Contains(parts[0], "(") {
+				layout.names[parts[0]], param = i, parts[1]
+			}
+			layout.elements = append(layout.elements, newTextLayout(param))
+		}
+	}
+	return layout
+}
+
+// element returns the layout of the i-th element of a tuple or map, l itself when the type
+// didn't say.
+func (l textLayout) element(i int) textLayout {
+	if i < len(l.elements) {
+		return l.elements[i]
+	}
+	return l
+}
+
+// field returns the layout of the element name of a named tuple.
+func (l textLayout) field(name string) textLayout {
+	if i, ok := l.names[name]; ok {
+		return l.elements[i]
+	}
+	return l
+}
+
+// splitTypeParams splits the parameters of a type at the top level commas, skipping the ones
+// of nested types and quoted enum names.
+func splitTypeParams(params string) []string {
+	var (
+		result   []string
+		start    int
+		brackets int
+		quoted   bool
+	)
+	for i := 0; i < len(params); i++ {
+		switch c := params[i]; {
+		case quoted && c == '\\':
+			i++
+		case quoted:
+			quoted = c != '\''
+		case c == '\'':
+			quoted = true
+		case c == '(':
+			brackets++
+		case c == ')':
+			brackets--
+		case c == ',' && brackets == 0:
+			result = append(result, strings.TrimSpace(params[start:i]))
+			start = i + 1
+		}
+	}
+	return append(result, strings.TrimSpace(params[start:]))
+}
+
+// textTimeLayout returns the layout of the date and time values of a column of type t.
+func textTimeLayout(t column.Type) string {
+	s := string(t)
+	switch i := strings.Index(s, "DateTime64("); {
+	case i != -1:
+		var precision int
+		if _, err := fmt.Sscanf(s[i:], "DateTime64(%d", &precision); err == nil && precision > 0 {
+			return "2006-01-02 15:04:05." + strings.Repeat("0", precision)
+		}
+		return "2006-01-02 15:04:05"
+	case strings.Contains(s, "DateTime"):
+		return "2006-01-02 15:04:05"
+	}
+	return "2006-01-02"
+}
+
+// appendEscaped escapes s the way the TabSeparated format and quoted strings do.
+func appendEscaped(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\b':
+			buf = append(buf, `\b`...)
+		case '\f':
+			buf = append(buf, `\f`...)
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		case '\t':
+			buf = append(buf, `\t`...)
+		case 0:
+			buf = append(buf, `\0`...)
+		case '\'', '\\':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// appendCSVString quotes s with double quotes, doubling the ones in s.
+func appendCSVString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			buf = append(buf, '"')
+		}
+		buf = append(buf, s[i])
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,148 @@
+// Licensed to ClickHouse, Inc. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. ClickHouse, Inc. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	block := &proto.Block{}
+	for _, c := range []struct {
+		name   string
+		chType column.Type
+	}{
+		{"id", "UInt64"},
+		{"name", "Nullable(String)"},
+		{"ratio", "Float64"},
+		{"price", "Decimal(10, 2)"},
+		{"ok", "Bool"},
+		{"day", "Date"},
+		{"at", "DateTime64(3, 'UTC')"},
+		{"uid", "UUID"},
+		{"tags", "Array(String)"},
+		{"attrs", "Map(String, UInt8)"},
+		{"pair", "Tuple(UInt8, String)"},
+		{"p", "Point"},
+	} {
+		require.NoError(t, block.AddColumn(c.name, c.chType))
+	}
+	var (
+		name = "tab\there, it's \"quoted\"\nand\\more"
+		at   = time.Date(2024, 3, 1, 12, 30, 45, 120_000_000, time.UTC)
+		uid  = uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	)
+	require.NoError(t, block.Append(
+		uint64(1), &name, 0.5, decimal.RequireFromString("12.30"), true, at, at, uid,
+		[]string{"a", "b'c"}, map[string]uint8{"k": 1}, []any{uint8(7), "x"}, [2]float64{1.5, -2},
+	))
+	require.NoError(t, block.Append(
+		uint64(2), nil, math.Inf(-1), decimal.Zero, false, at, at, uid,
+		[]string{}, map[string]uint8{}, []any{uint8(0), ""}, [2]float64{0, 1e21},
+	))
+
+	write := func(format TextFormat, header bool) string {
+		var sb strings.Builder
+		r := &rows{block: block, columns: block.ColumnsNames()}
+		require.NoError(t, WriteText(&sb, r, format, header))
+		return sb.String()
+	}
+
+	assert.Equal(t, "id\tname\tratio\tprice\tok\tday\tat\tuid\ttags\tattrs\tpair\tp\n"+
+		"1\ttab\\there, it\\'s \"quoted\"\\nand\\\\more\t0.5\t12.3\ttrue\t2024-03-01\t2024-03-01 12:30:45.120\t6ba7b810-9dad-11d1-80b4-00c04fd430c8\t['a','b\\'c']\t{'k':1}\t(7,'x')\t(1.5,-2)\n"+
+		"2\t\\N\t-inf\t0\tfalse\t2024-03-01\t2024-03-01 12:30:45.120\t6ba7b810-9dad-11d1-80b4-00c04fd430c8\t[]\t{}\t(0,'')\t(0,1e21)\n",
+		write(TSV, true))
+
+	assert.Equal(t, "1,\"tab\there, it's \"\"quoted\"\"\nand\\more\",0.5,12.3,true,\"2024-03-01\",\"2024-03-01 12:30:45.120\",\"6ba7b810-9dad-11d1-80b4-00c04fd430c8\",\"['a','b\\'c']\",\"{'k':1}\",\"(7,'x')\",\"(1.5,-2)\"\n"+
+		"2,\\N,-inf,0,false,\"2024-03-01\",\"2024-03-01 12:30:45.120\",\"6ba7b810-9dad-11d1-80b4-00c04fd430c8\",\"[]\",\"{}\",\"(0,'')\",\"(0,1e21)\"\n",
+		write(CSV, false))
+
+	assert.True(t, strings.HasPrefix(write(CSV, true), "\"id\",\"name\",\"ratio\""))
+}
+
+func TestWriteTextNestedTimeLayouts(t *testing.T) {
+	block := &proto.Block{}
+	for _, c := range []struct {
+		name   string
+		chType column.Type
+	}{
+		{"pair", "Tuple(Date, DateTime('UTC'))"},
+		{"named", "Tuple(day Date, at DateTime64(3, 'UTC'))"},
+		{"days", "Map(Date, DateTime('UTC'))"},
+		{"list", "Array(Tuple(Nullable(Date), DateTime('UTC')))"},
+	} {
+		require.NoError(t, block.AddColumn(c.name, c.chType))
+	}
+	var (
+		day = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		at  = time.Date(2024, 3, 1, 12, 30, 45, 120_000_000, time.UTC)
+	)
+	require.NoError(t, block.Append(
+		[]any{day, at},
+		map[string]any{"day": day, "at": at},
+		map[time.Time]time.Time{day: at},
+		[][]any{{&day, at}},
+	))
+
+	var sb strings.Builder
+	r := &rows{block: block, columns: block.ColumnsNames()}
+	require.NoError(t, WriteText(&sb, r, TSV, false))
+	assert.Equal(t, "('2024-03-01','2024-03-01 12:30:45')\t('2024-03-01','2024-03-01 12:30:45.120')\t"+
+		"{'2024-03-01':'2024-03-01 12:30:45'}\t[('2024-03-01','2024-03-01 12:30:45')]\n", sb.String())
+}
+
+func TestWriteTextQuery(t *testing.T) {
+	srv := newFakeServer().blocks(t, 2, 2)
+	conn, err := Open(&Options{DialContext: srv.dial})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.Query(context.Background(), "SELECT id, name, code FROM t")
+	require.NoError(t, err)
+	var sb strings.Builder
+	require.NoError(t, WriteText(&sb, rows, TSV, false))
+	assert.Equal(t, 4, strings.Count(sb.String(), "\n"), "every block is written")
+	assert.Equal(t, 1, conn.Stats().Idle, "the rows are closed once written")
+}
+
+func TestTextFloat(t *testing.T) {
+	for v, expected := range map[float64]string{
+		0:        "0",
+		-1.25:    "-1.25",
+		1e6:      "1000000",
+		1e-6:     "0.000001",
+		1e-7:     "1e-7",
+		1e21:     "1e21",
+		-2.5e300: "-2.5e300",
+	} {
+		assert.Equal(t, expected, string(appendTextFloat(nil, v, 64)))
+	}
+	assert.Equal(t, "nan", string(appendTextFloat(nil, math.NaN(), 64)))
+	assert.Equal(t, "0.1", string(appendTextFloat(nil, float64(float32(0.1)), 32)))
+}